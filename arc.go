@@ -0,0 +1,109 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Arc represents a stroked portion of a circumference.
+type Arc struct {
+	Base
+
+	// Radius of the arc, measured at the middle of the stroke
+	radius float32
+
+	// Stroke width
+	width float32
+
+	// Start and end angles in degrees
+	startAngle, endAngle float32
+
+	// Number of segments approximating the arc
+	segments int
+}
+
+// NewArc creates a new arc centered at (0, 0). It takes as arguments
+// a linked program, the radius, the stroke width, the start and end
+// angles in degrees (counterclockwise from the positive x axis) and
+// the number of segments used to approximate the arc. The number of
+// segments doesn't depend on the angles so that they can be changed
+// without reallocating the vertices.
+func NewArc(program shaders.Program, radius, width, startAngle, endAngle float32, segments int) *Arc {
+
+	arc := new(Arc)
+
+	if segments < 1 {
+		segments = 1
+	}
+
+	arc.radius = radius
+	arc.width = width
+	arc.segments = segments
+	arc.vertices = make([]float32, 0, (segments+1)*4)
+	arc.SetAngles(startAngle, endAngle)
+
+	// Set the default color
+	arc.SetColor(DefaultColor)
+
+	arc.initProgram(program)
+
+	// Start with no rotation and unit scale.
+	arc.initTransform()
+
+	return arc
+}
+
+// Angles returns the start and end angles of the arc in degrees.
+func (arc *Arc) Angles() (float32, float32) {
+	return arc.startAngle, arc.endAngle
+}
+
+// SetAngles sets the start and end angles of the arc in degrees. The
+// vertices are rebuilt in place.
+func (arc *Arc) SetAngles(startAngle, endAngle float32) {
+	arc.startAngle, arc.endAngle = startAngle, endAngle
+
+	// The stroke is a triangle strip alternating points on the
+	// outer and inner border.
+	outer, inner := arc.radius+arc.width/2, arc.radius-arc.width/2
+	start, sweep := radians(startAngle), radians(endAngle-startAngle)
+	arc.vertices = arc.vertices[:0]
+	for i := 0; i <= arc.segments; i++ {
+		a := start + sweep*float64(i)/float64(arc.segments)
+		cos, sin := float32(math.Cos(a)), float32(math.Sin(a))
+		arc.vertices = append(arc.vertices, outer*cos, outer*sin, inner*cos, inner*sin)
+	}
+	arc.updateBounds()
+}
+
+// Contains returns true if (x, y), in world coordinates, lies on the
+// arc.
+func (arc *Arc) Contains(x, y float32) bool {
+	return arc.containsLocal(gl.TRIANGLE_STRIP, nil, x, y)
+}
+
+// hulls returns the triangles of the arc.
+func (arc *Arc) hulls() []hull {
+	return arc.triangleHulls(gl.TRIANGLE_STRIP, nil)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the arc within
+// maxDist.
+func (arc *Arc) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(arc, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the arc on the surface.
+func (arc *Arc) Draw() {
+	arc.drawArrays(gl.TRIANGLE_STRIP)
+}
+
+// Clone makes a copy of the arc.
+func (arc *Arc) Clone() Shape {
+	a := NewArc(arc.program, arc.radius, arc.width, arc.startAngle, arc.endAngle, arc.segments)
+	a.copyColors(&arc.Base)
+	return a
+}
@@ -1,10 +1,11 @@
 package shapes
 
 import (
-	"image"
+	"fmt"
 	"image/color"
 
 	"github.com/remogatto/mathgl"
+	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shaders"
 )
 
@@ -13,19 +14,15 @@ type Base struct {
 	// Vertices of the generic shape
 	vertices []float32
 
-	// Matrices
-	projMatrix  mathgl.Mat4f
-	modelMatrix mathgl.Mat4f
-	viewMatrix  mathgl.Mat4f
-
-	// Center of the shape
-	x, y float32
+	// Position, rotation and scale
+	transform
 
-	// Angle
-	angle float32
+	// Matrices
+	projMatrix mathgl.Mat4f
+	viewMatrix mathgl.Mat4f
 
-	// Bounds
-	bounds image.Rectangle
+	// Rectangle enclosing the untransformed vertices
+	localBounds Rect
 
 	// Color
 	color color.Color
@@ -40,9 +37,16 @@ type Base struct {
 	texBuffer uint32
 	texCoords []float32
 
+	// Buffers storing vertices, colors and texture coordinates on
+	// the GPU
+	vertexBuffers
+
 	// GLSL program
 	program shaders.Program
 
+	// Whether the program declares the texture variables
+	textured bool
+
 	// GLSL variables IDs
 	colorId       uint32
 	posId         uint32
@@ -52,45 +56,104 @@ type Base struct {
 	texInId       uint32
 	texRatioId    uint32
 	textureId     uint32
+
+	// Batch collecting the shape while it's drawn, if any
+	batch *Batch
 }
 
-// Rotate rotates the shape around its center, by the given angle in
-// degrees.
-func (b *Base) Rotate(angle float32) {
-	b.modelMatrix = mathgl.Translate3D(b.x, b.y, 0).Mul4(mathgl.HomogRotate3DZ(angle))
-	b.angle = angle
+// initProgram sets the program used to render the shape and gets the
+// IDs of the position, color and matrices variables declared by the
+// shaders.
+func (b *Base) initProgram(program shaders.Program) {
+	b.program = program
+	b.program.Use()
+
+	// Get variables IDs from shaders
+	b.posId = program.GetAttribute("pos")
+	b.colorId = program.GetAttribute("color")
+	b.projMatrixId = program.GetUniform("projection")
+	b.modelMatrixId = program.GetUniform("model")
+	b.viewMatrixId = program.GetUniform("view")
 }
 
-// RotateAround rotates the shape around the given point, by the given
-// angle in degrees.
-func (b *Base) RotateAround(x, y, angle float32) {
-	dx, dy := x-b.x, y-b.y
-	b.modelMatrix = mathgl.Translate3D(x, y, 0).Mul4(mathgl.HomogRotate3DZ(angle))
-	b.modelMatrix = b.modelMatrix.Mul4(mathgl.Translate3D(-dx, -dy, 0))
-	b.angle = angle
+// initTexturedProgram is like initProgram but it also gets the IDs
+// of the texture variables declared by the shaders (see DefaultBoxVS
+// and DefaultBoxFS).
+func (b *Base) initTexturedProgram(program shaders.Program) {
+	b.initProgram(program)
+	b.texInId = program.GetAttribute("texIn")
+	b.textureId = program.GetUniform("texture")
+	b.texRatioId = program.GetUniform("texRatio")
+	b.textured = true
 }
 
-// Scale scales the shape relative to its center, by the given
-// factors.
-func (b *Base) Scale(sx, sy float32) {
-	b.modelMatrix = mathgl.Translate3D(b.x, b.y, 0).Mul4(mathgl.Scale3D(sx, sy, 1.0))
+// bind sets the program, the attributes and the uniforms needed to
+// render the shape with the given model matrix.
+func (b *Base) bind(modelMatrix mathgl.Mat4f) {
+	b.program.Use()
+
+	b.bindBuffer(positionBuffer, b.vertices, b.posId, 2)
+	b.bindBuffer(colorBuffer, b.vColor, b.colorId, 4)
+
+	gl.UniformMatrix4fv(int32(b.modelMatrixId), 1, false, (*float32)(&modelMatrix[0]))
+	gl.UniformMatrix4fv(int32(b.projMatrixId), 1, false, (*float32)(&b.projMatrix[0]))
+	gl.UniformMatrix4fv(int32(b.viewMatrixId), 1, false, (*float32)(&b.viewMatrix[0]))
 
+	// Texture
+	if b.textured {
+		gl.Uniform1f(int32(b.texRatioId), 0.0)
+		if len(b.texCoords) > 0 {
+			gl.Uniform1f(int32(b.texRatioId), 1.0)
+			b.bindBuffer(texCoordBuffer, b.texCoords, b.texInId, 2)
+			gl.ActiveTexture(gl.TEXTURE0)
+			gl.BindTexture(gl.TEXTURE_2D, b.texBuffer)
+			gl.Uniform1i(int32(b.textureId), 0)
+		}
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// drawArrays renders all the vertices of the shape using the given
+// primitive mode. If the shape is drawn in a batch or in a frame,
+// the vertices are just enqueued.
+func (b *Base) drawArrays(mode gl.Enum) {
+	b.draw(mode, nil)
 }
 
-// Move moves the shape by dx, dy.
-func (b *Base) Move(dx, dy float32) {
-	b.modelMatrix = b.modelMatrix.Mul4(mathgl.Translate3D(dx, dy, 0))
-	b.bounds = b.bounds.Add(image.Point{int(dx), int(dy)})
-	b.x, b.y = b.x+dx, b.y+dy
+// drawElements renders the vertices of the shape referenced by
+// indices using the given primitive mode. If the shape is drawn in a
+// batch or in a frame, the vertices are just enqueued.
+func (b *Base) drawElements(mode gl.Enum, indices []uint16) {
+	b.draw(mode, indices)
 }
 
-// MoveTo moves the shape in x, y position.
-func (b *Base) MoveTo(x, y float32) {
-	b.modelMatrix = mathgl.Translate3D(x, y, 0)
-	dx := x - b.x
-	dy := y - b.y
-	b.x, b.y = x, y
-	b.bounds = b.bounds.Add(image.Point{int(dx), int(dy)})
+// draw renders the vertices of the shape, listed by indices if not
+// nil, or enqueues them in the batch or in the frame being built.
+func (b *Base) draw(mode gl.Enum, indices []uint16) {
+	switch {
+	case b.batch != nil:
+		if b.batch.add(b, b.worldMatrix(), mode, indices) {
+			return
+		}
+	case current != nil:
+		current.enqueue(b, mode, indices)
+		return
+	}
+	b.submit(b.worldMatrix(), mode, indices)
+	finishDraw()
+}
+
+// submit issues the draw call rendering the vertices of the shape,
+// listed by indices if not nil, transformed by the given model
+// matrix.
+func (b *Base) submit(modelMatrix mathgl.Mat4f, mode gl.Enum, indices []uint16) {
+	b.bind(modelMatrix)
+	if indices == nil {
+		gl.DrawArrays(mode, 0, gl.Sizei(len(b.vertices)/2))
+		return
+	}
+	gl.DrawElements(mode, gl.Sizei(len(indices)), gl.UNSIGNED_SHORT, gl.Void(&indices[0]))
 }
 
 // Vertices returns the vertices slice.
@@ -98,25 +161,51 @@ func (b *Base) Vertices() []float32 {
 	return b.vertices
 }
 
-// Center returns the coordinates of the transformed center of the
-// shape.
-func (b *Base) Center() (float32, float32) {
-	return b.x, b.y
+// Bounds returns the bounds of the shape. The bounds
+// enclose the vertices of the shape once transformed, in the
+// coordinates of the group containing it.
+func (b *Base) Bounds() Rect {
+	return rectOf(b.appendPoints(nil, mathgl.Ident4f()))
+}
+
+// WorldVertices returns the vertices of the shape transformed by its
+// model matrix and by the ones of the groups containing it.
+func (b *Base) WorldVertices() []float32 {
+	return appendTransformed(nil, b.vertices, b.worldMatrix())
 }
 
-// SetCenter sets the coordinates of the center of the shape.
-func (b *Base) SetCenter(x, y float32) {
-	b.x, b.y = x, y
+// AABB returns the axis-aligned rectangle enclosing the shape in
+// world coordinates, after all the transformations are applied.
+func (b *Base) AABB() Rect {
+	return rectOf(b.appendPoints(nil, b.parentMatrix()))
 }
 
-// Angle returns the current angle of the shape in degrees.
-func (b *Base) Angle() float32 {
-	return b.angle
+// OBB returns the smallest box enclosing the shape in world
+// coordinates, oriented as the shape.
+func (b *Base) OBB() OBB {
+	return orientedBounds(b.appendPoints(nil, b.parentMatrix()), b.worldMatrix())
 }
 
-// Bounds returns the bounds of the shape as a Rectangle.
-func (b *Base) Bounds() image.Rectangle {
-	return b.bounds
+// appendPoints appends to points the vertices of the shape, or the
+// corners of its bounds if it has no vertices, transformed by its
+// model matrix and then by m.
+func (b *Base) appendPoints(points []float32, m mathgl.Mat4f) []float32 {
+	b.updateMatrix()
+	m = m.Mul4(b.matrix)
+	if len(b.vertices) < 2 {
+		r := b.localBounds
+		return appendTransformed(points, []float32{r.MinX, r.MinY, r.MaxX, r.MinY, r.MaxX, r.MaxY, r.MinX, r.MaxY}, m)
+	}
+	return appendTransformed(points, b.vertices, m)
+}
+
+// SetAnchor sets the pivot of the shape in coordinates normalized to
+// the rectangle enclosing its untransformed vertices: (0, 0) is the
+// bottom-left corner, (0.5, 1) the top-center and (1, 1) the
+// top-right one.
+func (b *Base) SetAnchor(ax, ay float32) {
+	r := b.localBounds
+	b.SetPivot(r.MinX+ax*r.Dx(), r.MinY+ay*r.Dy())
 }
 
 // Color returns the color of the shape.
@@ -135,24 +224,69 @@ func (s *Base) SetColor(c color.Color) {
 
 	s.color = c
 
+	// Normalize the color components
+	s.nColor = normalizeColor(c)
+
+	// TODO improve code
+	vCount := len(s.vertices) / 2
+	s.vColor = s.vColor[:0]
+	for i := 0; i < vCount; i++ {
+		s.vColor = append(s.vColor, s.nColor[0], s.nColor[1], s.nColor[2], s.nColor[3])
+	}
+	s.markStale(colorBuffer)
+}
+
+// SetVertexColors sets a color for each vertex of the shape. Colors
+// are interpolated across the primitives, so it can be used to
+// render gradients. Color and NColor are not affected, and a
+// subsequent call to SetColor restores a uniform color. It returns
+// an error if the number of colors doesn't match the number of
+// vertices.
+func (s *Base) SetVertexColors(colors []color.Color) error {
+	vCount := len(s.vertices) / 2
+	if len(colors) != vCount {
+		return fmt.Errorf("the shape has %d vertices but %d colors were given", vCount, len(colors))
+	}
+	s.vColor = s.vColor[:0]
+	for _, c := range colors {
+		nc := normalizeColor(c)
+		s.vColor = append(s.vColor, nc[0], nc[1], nc[2], nc[3])
+	}
+	s.markStale(colorBuffer)
+	return nil
+}
+
+// updateColors sets the uniform color of the shape on its vertices,
+// unless the vertex colors set before still match their number. It's
+// meant to be called after rebuilding the vertices.
+func (b *Base) updateColors() {
+	if len(b.vColor) > 0 && len(b.vColor) == len(b.vertices)/2*4 {
+		return
+	}
+	b.SetColor(b.color)
+}
+
+// copyColors copies the color and the vertex colors of src, which
+// must have the same number of vertices.
+func (b *Base) copyColors(src *Base) {
+	b.color, b.nColor = src.color, src.nColor
+	b.vColor = append(b.vColor[:0], src.vColor...)
+	b.markStale(colorBuffer)
+}
+
+// normalizeColor returns the RGBA components of the given color as
+// float32 values in the range [0, 1].
+func normalizeColor(c color.Color) [4]float32 {
 	// Convert to RGBA
 	rgba := color.NRGBAModel.Convert(c).(color.NRGBA)
 	r, g, b, a := rgba.R, rgba.G, rgba.B, rgba.A
 
-	// Normalize the color components
-	s.nColor = [4]float32{
+	return [4]float32{
 		float32(r) / 255,
 		float32(g) / 255,
 		float32(b) / 255,
 		float32(a) / 255,
 	}
-
-	// TODO improve code
-	vCount := len(s.vertices) / 2
-	s.vColor = s.vColor[:0]
-	for i := 0; i < vCount; i++ {
-		s.vColor = append(s.vColor, s.nColor[0], s.nColor[1], s.nColor[2], s.nColor[3])
-	}
 }
 
 // AttachToWorld fills projection and view matrices with world's
@@ -168,10 +302,45 @@ func (b *Base) AttachToWorld(world World) {
 func (b *Base) SetTexture(texture uint32, texCoords []float32) error {
 	b.texCoords = texCoords
 	b.texBuffer = texture
+	b.markStale(texCoordBuffer)
 	return nil
 }
 
+// boundsTexCoords returns texture coordinates mapping the whole
+// texture on the rectangle enclosing the vertices of the shape.
+func (b *Base) boundsTexCoords() []float32 {
+	if len(b.vertices) < 2 {
+		return nil
+	}
+	minX, minY, maxX, maxY := boundsOf(b.vertices)
+	w, h := maxX-minX, maxY-minY
+	texCoords := make([]float32, len(b.vertices))
+	for i := 0; i < len(b.vertices); i += 2 {
+		if w > 0 {
+			texCoords[i] = (b.vertices[i] - minX) / w
+		}
+		if h > 0 {
+			texCoords[i+1] = (b.vertices[i+1] - minY) / h
+		}
+	}
+	return texCoords
+}
+
+// updateBounds sets the local bounds of the shape to the rectangle
+// enclosing its vertices.
+func (b *Base) updateBounds() {
+	b.setLocalBounds(boundsOf(b.vertices))
+}
+
+// setLocalBounds sets the rectangle enclosing the untransformed
+// shape.
+func (b *Base) setLocalBounds(minX, minY, maxX, maxY float32) {
+	b.localBounds = Rect{minX, minY, maxX, maxY}
+	b.markStale(positionBuffer)
+	b.boundsChanged()
+}
+
 // String returns a string representation of the shape.
 func (b *Base) String() string {
-	return b.bounds.String()
+	return b.Bounds().String()
 }
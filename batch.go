@@ -0,0 +1,194 @@
+package shapes
+
+import (
+	"github.com/remogatto/mathgl"
+	gl "github.com/remogatto/opengles2"
+)
+
+// maxBatchVertices is the number of vertices that can be referenced
+// by 16-bit indices.
+const maxBatchVertices = 1 << 16
+
+// Batch merges the triangles of many shapes in as few draw calls as
+// possible. Consecutive shapes sharing the same program, texture and
+// projection and view matrices are transformed on the CPU by their
+// model and view matrices, in the same order as the shaders do, and
+// rendered with a single call to DrawElements. Shapes
+// rendered as lines are drawn on their own, flushing the triangles
+// collected so far, so that the drawing order is preserved.
+//
+// Groups use a batch to draw their children.
+type Batch struct {
+	// State shared by the collected shapes and used to render
+	// them. Its transform and view matrix are the identity, since
+	// vertices are already transformed by the ones of the shapes.
+	state Base
+
+	// View matrix of the collected shapes
+	view mathgl.Mat4f
+
+	// Whether some triangles were collected
+	pending bool
+
+	// Collected geometry
+	indices []uint16
+
+	// Number of draw calls since Begin
+	drawCalls int
+}
+
+// baser is implemented by the shapes embedding Base.
+type baser interface {
+	base() *Base
+}
+
+// base returns the Base embedded in a shape.
+func (b *Base) base() *Base {
+	return b
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	batch := new(Batch)
+	batch.state.initTransform()
+	batch.state.streamed = true
+	return batch
+}
+
+// Begin starts collecting shapes, discarding the ones collected and
+// not rendered yet.
+func (batch *Batch) Begin() {
+	batch.reset()
+	batch.drawCalls = 0
+}
+
+// Add renders s through the batch. The children of groups are added
+// recursively. Shapes not embedding Base are drawn on their own.
+func (batch *Batch) Add(s Shape) {
+	switch v := s.(type) {
+	case *Group:
+		v.rwMutex.RLock()
+		defer v.rwMutex.RUnlock()
+		for _, c := range v.children {
+			batch.Add(c)
+		}
+	case baser:
+		b := v.base()
+		b.batch = batch
+		s.Draw()
+		b.batch = nil
+	default:
+		batch.Flush()
+		s.Draw()
+		batch.drawCalls++
+	}
+}
+
+// End renders the shapes collected so far.
+func (batch *Batch) End() {
+	batch.Flush()
+}
+
+// Flush renders the triangles collected so far with a single draw
+// call.
+func (batch *Batch) Flush() {
+	if !batch.pending {
+		return
+	}
+	if len(batch.indices) > 0 {
+		batch.state.submit(mathgl.Ident4f(), gl.TRIANGLES, batch.indices)
+		batch.drawCalls++
+		finishDraw()
+	}
+	batch.reset()
+}
+
+// DrawCalls returns the number of draw calls issued since Begin.
+func (batch *Batch) DrawCalls() int {
+	return batch.drawCalls
+}
+
+// reset discards the collected geometry.
+func (batch *Batch) reset() {
+	s := &batch.state
+	s.vertices = s.vertices[:0]
+	s.vColor = s.vColor[:0]
+	s.texCoords = s.texCoords[:0]
+	batch.indices = batch.indices[:0]
+	batch.pending = false
+	for i := 0; i < bufferCount; i++ {
+		s.markStale(i)
+	}
+}
+
+// Release frees the buffers used to render the collected shapes on
+// the GPU.
+func (batch *Batch) Release() {
+	batch.state.Release()
+}
+
+// add collects the triangles described by the vertices of b,
+// transformed by the given model matrix, in the given primitive
+// mode, listed by indices if not nil. It returns false
+// if the shape can't be batched and must be drawn on its own, after
+// rendering the triangles collected so far.
+func (batch *Batch) add(b *Base, modelMatrix mathgl.Mat4f, mode gl.Enum, indices []uint16) bool {
+	n := len(b.vertices) / 2
+	batchable := mode == gl.TRIANGLES || mode == gl.TRIANGLE_STRIP || mode == gl.TRIANGLE_FAN
+	if !batchable || n > maxBatchVertices || len(b.vColor) != n*4 || b.hasTexCoords() && len(b.texCoords) != n*2 {
+		batch.Flush()
+		batch.drawCalls++
+		return false
+	}
+
+	if batch.pending && (!batch.compatible(b) || len(batch.state.vertices)/2+n > maxBatchVertices) {
+		batch.Flush()
+	}
+	if !batch.pending {
+		batch.setState(b)
+	}
+
+	s := &batch.state
+	first := len(s.vertices) / 2
+	s.vertices = appendTransformed(s.vertices, b.vertices, modelMatrix.Mul4(b.viewMatrix))
+	s.vColor = append(s.vColor, b.vColor...)
+	if b.hasTexCoords() {
+		s.texCoords = append(s.texCoords, b.texCoords...)
+	}
+	forEachTriangle(n, mode, indices, func(i, j, k int) bool {
+		batch.indices = append(batch.indices, uint16(first+i), uint16(first+j), uint16(first+k))
+		return false
+	})
+	batch.pending = true
+	return true
+}
+
+// compatible returns true if b can be rendered with the shapes
+// collected so far.
+func (batch *Batch) compatible(b *Base) bool {
+	s := &batch.state
+	return s.program == b.program &&
+		s.textured == b.textured &&
+		(len(s.texCoords) > 0) == b.hasTexCoords() &&
+		(!b.hasTexCoords() || s.texBuffer == b.texBuffer) &&
+		s.projMatrix == b.projMatrix &&
+		batch.view == b.viewMatrix
+}
+
+// setState sets the program, the texture and the matrices used to
+// render the collected shapes to the ones of b.
+func (batch *Batch) setState(b *Base) {
+	s := &batch.state
+	s.program, s.textured = b.program, b.textured
+	s.posId, s.colorId = b.posId, b.colorId
+	s.projMatrixId, s.modelMatrixId, s.viewMatrixId = b.projMatrixId, b.modelMatrixId, b.viewMatrixId
+	s.texInId, s.texRatioId, s.textureId = b.texInId, b.texRatioId, b.textureId
+	s.texBuffer = b.texBuffer
+	s.projMatrix, s.viewMatrix = b.projMatrix, mathgl.Ident4f()
+	batch.view = b.viewMatrix
+}
+
+// hasTexCoords returns true if the shape is rendered with a texture.
+func (b *Base) hasTexCoords() bool {
+	return b.textured && len(b.texCoords) > 0
+}
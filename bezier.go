@@ -0,0 +1,325 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+var (
+	// DefaultCurveTolerance is the default maximum distance in
+	// pixels between a curve and the segments approximating it.
+	DefaultCurveTolerance float32 = 0.5
+)
+
+// maxFlattenDepth limits the recursive subdivision of curves.
+const maxFlattenDepth = 16
+
+// curve holds the state shared by the Bezier shapes. Curves are
+// flattened in a sequence of points and rendered like Segment: as a
+// line strip or, if the width is not zero, as a tessellated stroke.
+type curve struct {
+	Base
+
+	// Flattened points of the curve
+	points []float32
+
+	// Maximum distance between the curve and its flattened
+	// approximation
+	tolerance float32
+
+	// Stroke style
+	width   float32
+	cap     Cap
+	stroked bool
+
+	// flatten returns the flattened points of the curve
+	// appending them to the given slice.
+	flatten func(points []float32, tolerance float32) []float32
+}
+
+// init sets the default values and the program of the curve.
+func (c *curve) init(program shaders.Program) {
+	c.color = DefaultColor
+	c.tolerance = curveTolerance(DefaultCurveTolerance)
+	c.initProgram(program)
+
+	// Start with no rotation and unit scale.
+	c.initTransform()
+}
+
+// update flattens the curve and rebuilds its vertices.
+func (c *curve) update() {
+	c.points = c.flatten(c.points[:0], c.tolerance)
+
+	// The points are not relative to the center of the curve, so
+	// the pivot follows the center of the flattened points, unless
+	// a different one was set.
+	minX, minY, maxX, maxY := boundsOf(c.points)
+	c.centerPivot((minX+maxX)/2, (minY+maxY)/2)
+
+	c.tessellate()
+}
+
+// tessellate builds the vertices of the curve from its flattened
+// points and updates its colors and bounds accordingly.
+func (c *curve) tessellate() {
+	c.vertices = nil
+	if c.width > 0 {
+		c.vertices = stroke(c.points, c.width, MiterJoin, c.cap, DefaultMiterLimit)
+	}
+	c.stroked = c.vertices != nil
+	if !c.stroked {
+		c.vertices = c.points
+	}
+	c.SetColor(c.color)
+	c.updateBounds()
+}
+
+// Vertices returns the flattened points of the curve.
+func (c *curve) Vertices() []float32 {
+	return c.points
+}
+
+// Tolerance returns the maximum distance in pixels between the curve
+// and its flattened approximation.
+func (c *curve) Tolerance() float32 {
+	return c.tolerance
+}
+
+// SetTolerance sets the maximum distance in pixels between the curve
+// and its flattened approximation. A tolerance that is not positive
+// is replaced by DefaultCurveTolerance.
+func (c *curve) SetTolerance(tolerance float32) {
+	c.tolerance = curveTolerance(tolerance)
+	c.update()
+}
+
+// curveTolerance returns tolerance if it's positive, otherwise
+// DefaultCurveTolerance or, if that isn't positive either, 0.5.
+func curveTolerance(tolerance float32) float32 {
+	switch {
+	case tolerance > 0:
+		return tolerance
+	case DefaultCurveTolerance > 0:
+		return DefaultCurveTolerance
+	}
+	return 0.5
+}
+
+// Width returns the stroke width of the curve.
+func (c *curve) Width() float32 {
+	return c.width
+}
+
+// SetWidth sets the stroke width of the curve. A zero width renders
+// the curve as a line strip.
+func (c *curve) SetWidth(width float32) {
+	c.width = width
+	c.tessellate()
+}
+
+// SetCap sets the style used to terminate the ends of a curve with
+// non-zero width.
+func (c *curve) SetCap(endCap Cap) {
+	c.cap = endCap
+	c.tessellate()
+}
+
+// Contains returns true if (x, y), in world coordinates, lies on the
+// stroke of the curve or within DefaultHitTolerance from it.
+func (c *curve) Contains(x, y float32) bool {
+	if c.stroked && c.containsLocal(gl.TRIANGLE_STRIP, nil, x, y) {
+		return true
+	}
+	return c.nearLine(c.points, x, y)
+}
+
+// hulls returns the triangles of the stroke of the curve or its
+// flattened segments.
+func (c *curve) hulls() []hull {
+	if c.stroked {
+		return c.triangleHulls(gl.TRIANGLE_STRIP, nil)
+	}
+	return c.lineHulls(c.points)
+}
+
+// Draw actually renders the curve on the surface.
+func (c *curve) Draw() {
+	if len(c.vertices) < 4 {
+		return
+	}
+	if c.stroked {
+		c.drawArrays(gl.TRIANGLE_STRIP)
+	} else {
+		c.drawArrays(gl.LINE_STRIP)
+	}
+}
+
+// clone copies the style of the curve to dst.
+func (c *curve) clone(dst *curve) {
+	dst.tolerance = c.tolerance
+	dst.width = c.width
+	dst.cap = c.cap
+	dst.color = c.color
+	dst.update()
+}
+
+// QuadBezier represents a quadratic Bezier curve.
+type QuadBezier struct {
+	curve
+
+	// Control points
+	x0, y0, x1, y1, x2, y2 float32
+}
+
+// NewQuadBezier returns a new quadratic Bezier curve. It takes as
+// arguments a program shader (e.g. built from DefaultSegmentVS and
+// DefaultSegmentFS), the start point, the control point and the end
+// point of the curve.
+func NewQuadBezier(program shaders.Program, x0, y0, x1, y1, x2, y2 float32) *QuadBezier {
+	bezier := new(QuadBezier)
+	bezier.init(program)
+	bezier.flatten = func(points []float32, tolerance float32) []float32 {
+		points = append(points, bezier.x0, bezier.y0)
+		return flattenQuad(points, bezier.x0, bezier.y0, bezier.x1, bezier.y1, bezier.x2, bezier.y2, tolerance, 0)
+	}
+	bezier.SetControlPoints(x0, y0, x1, y1, x2, y2)
+	return bezier
+}
+
+// ControlPoints returns the start point, the control point and the
+// end point of the curve.
+func (bezier *QuadBezier) ControlPoints() (x0, y0, x1, y1, x2, y2 float32) {
+	return bezier.x0, bezier.y0, bezier.x1, bezier.y1, bezier.x2, bezier.y2
+}
+
+// SetControlPoints sets the start point, the control point and the
+// end point of the curve and flattens it again.
+func (bezier *QuadBezier) SetControlPoints(x0, y0, x1, y1, x2, y2 float32) {
+	bezier.x0, bezier.y0 = x0, y0
+	bezier.x1, bezier.y1 = x1, y1
+	bezier.x2, bezier.y2 = x2, y2
+	bezier.update()
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the curve within
+// maxDist.
+func (bezier *QuadBezier) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(bezier, originX, originY, dirX, dirY, maxDist)
+}
+
+// Clone makes a copy of the curve.
+func (bezier *QuadBezier) Clone() Shape {
+	b := NewQuadBezier(
+		bezier.program,
+		bezier.x0, bezier.y0,
+		bezier.x1, bezier.y1,
+		bezier.x2, bezier.y2,
+	)
+	bezier.clone(&b.curve)
+	return b
+}
+
+// CubicBezier represents a cubic Bezier curve.
+type CubicBezier struct {
+	curve
+
+	// Control points
+	x0, y0, x1, y1, x2, y2, x3, y3 float32
+}
+
+// NewCubicBezier returns a new cubic Bezier curve. It takes as
+// arguments a program shader (e.g. built from DefaultSegmentVS and
+// DefaultSegmentFS), the start point, the two control points and the
+// end point of the curve.
+func NewCubicBezier(program shaders.Program, x0, y0, x1, y1, x2, y2, x3, y3 float32) *CubicBezier {
+	bezier := new(CubicBezier)
+	bezier.init(program)
+	bezier.flatten = func(points []float32, tolerance float32) []float32 {
+		points = append(points, bezier.x0, bezier.y0)
+		return flattenCubic(points, bezier.x0, bezier.y0, bezier.x1, bezier.y1, bezier.x2, bezier.y2, bezier.x3, bezier.y3, tolerance, 0)
+	}
+	bezier.SetControlPoints(x0, y0, x1, y1, x2, y2, x3, y3)
+	return bezier
+}
+
+// ControlPoints returns the start point, the two control points and
+// the end point of the curve.
+func (bezier *CubicBezier) ControlPoints() (x0, y0, x1, y1, x2, y2, x3, y3 float32) {
+	return bezier.x0, bezier.y0, bezier.x1, bezier.y1, bezier.x2, bezier.y2, bezier.x3, bezier.y3
+}
+
+// SetControlPoints sets the start point, the two control points and
+// the end point of the curve and flattens it again.
+func (bezier *CubicBezier) SetControlPoints(x0, y0, x1, y1, x2, y2, x3, y3 float32) {
+	bezier.x0, bezier.y0 = x0, y0
+	bezier.x1, bezier.y1 = x1, y1
+	bezier.x2, bezier.y2 = x2, y2
+	bezier.x3, bezier.y3 = x3, y3
+	bezier.update()
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the curve within
+// maxDist.
+func (bezier *CubicBezier) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(bezier, originX, originY, dirX, dirY, maxDist)
+}
+
+// Clone makes a copy of the curve.
+func (bezier *CubicBezier) Clone() Shape {
+	b := NewCubicBezier(
+		bezier.program,
+		bezier.x0, bezier.y0,
+		bezier.x1, bezier.y1,
+		bezier.x2, bezier.y2,
+		bezier.x3, bezier.y3,
+	)
+	bezier.clone(&b.curve)
+	return b
+}
+
+// flattenQuad appends to points the end points of the segments
+// approximating the quadratic Bezier curve within the given
+// tolerance. The start point is not appended.
+func flattenQuad(points []float32, x0, y0, x1, y1, x2, y2, tolerance float32, depth int) []float32 {
+	// The distance between the curve and its chord is at most a
+	// quarter of the second difference of the control points.
+	dx, dy := x0-2*x1+x2, y0-2*y1+y2
+	if depth >= maxFlattenDepth || math.Hypot(float64(dx), float64(dy))/4 <= float64(tolerance) {
+		return append(points, x2, y2)
+	}
+
+	// Subdivide the curve at t = 0.5 (de Casteljau)
+	ax, ay := (x0+x1)/2, (y0+y1)/2
+	bx, by := (x1+x2)/2, (y1+y2)/2
+	mx, my := (ax+bx)/2, (ay+by)/2
+	points = flattenQuad(points, x0, y0, ax, ay, mx, my, tolerance, depth+1)
+	return flattenQuad(points, mx, my, bx, by, x2, y2, tolerance, depth+1)
+}
+
+// flattenCubic appends to points the end points of the segments
+// approximating the cubic Bezier curve within the given tolerance.
+// The start point is not appended.
+func flattenCubic(points []float32, x0, y0, x1, y1, x2, y2, x3, y3, tolerance float32, depth int) []float32 {
+	// The distance between the curve and its chord is at most 3/4
+	// of the largest second difference of the control points.
+	d1 := math.Hypot(float64(x0-2*x1+x2), float64(y0-2*y1+y2))
+	d2 := math.Hypot(float64(x1-2*x2+x3), float64(y1-2*y2+y3))
+	if depth >= maxFlattenDepth || math.Max(d1, d2)*3/4 <= float64(tolerance) {
+		return append(points, x3, y3)
+	}
+
+	// Subdivide the curve at t = 0.5 (de Casteljau)
+	ax, ay := (x0+x1)/2, (y0+y1)/2
+	bx, by := (x1+x2)/2, (y1+y2)/2
+	cx, cy := (x2+x3)/2, (y2+y3)/2
+	abx, aby := (ax+bx)/2, (ay+by)/2
+	bcx, bcy := (bx+cx)/2, (by+cy)/2
+	mx, my := (abx+bcx)/2, (aby+bcy)/2
+	points = flattenCubic(points, x0, y0, ax, ay, abx, aby, mx, my, tolerance, depth+1)
+	return flattenCubic(points, mx, my, bcx, bcy, cx, cy, x3, y3, tolerance, depth+1)
+}
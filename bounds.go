@@ -0,0 +1,211 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/remogatto/mathgl"
+)
+
+// Rect is an axis-aligned rectangle with float32 coordinates. A
+// rectangle is empty if its maximum coordinates are not greater than
+// the minimum ones.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float32
+}
+
+// NewRect returns the rectangle with corners (x0, y0) and (x1, y1),
+// swapping the coordinates if needed so that the minimum ones come
+// first.
+func NewRect(x0, y0, x1, y1 float32) Rect {
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	return Rect{x0, y0, x1, y1}
+}
+
+// FromRectangle converts an image.Rectangle in a Rect.
+func FromRectangle(r image.Rectangle) Rect {
+	return Rect{float32(r.Min.X), float32(r.Min.Y), float32(r.Max.X), float32(r.Max.Y)}
+}
+
+// Rectangle returns the smallest image.Rectangle containing r.
+func (r Rect) Rectangle() image.Rectangle {
+	return image.Rect(
+		int(math.Floor(float64(r.MinX))), int(math.Floor(float64(r.MinY))),
+		int(math.Ceil(float64(r.MaxX))), int(math.Ceil(float64(r.MaxY))),
+	)
+}
+
+// String returns a string representation of r like
+// "(3,4.5)-(6,8)".
+func (r Rect) String() string {
+	return fmt.Sprintf("(%g,%g)-(%g,%g)", r.MinX, r.MinY, r.MaxX, r.MaxY)
+}
+
+// Dx returns the width of r.
+func (r Rect) Dx() float32 {
+	return r.MaxX - r.MinX
+}
+
+// Dy returns the height of r.
+func (r Rect) Dy() float32 {
+	return r.MaxY - r.MinY
+}
+
+// Center returns the coordinates of the center of r.
+func (r Rect) Center() (float32, float32) {
+	return (r.MinX + r.MaxX) / 2, (r.MinY + r.MaxY) / 2
+}
+
+// Empty reports whether r contains no points.
+func (r Rect) Empty() bool {
+	return r.MinX >= r.MaxX || r.MinY >= r.MaxY
+}
+
+// Contains reports whether the point (x, y) is inside r or on its
+// border.
+func (r Rect) Contains(x, y float32) bool {
+	return r.MinX <= x && x <= r.MaxX && r.MinY <= y && y <= r.MaxY
+}
+
+// Union returns the smallest rectangle containing both r and s. The
+// zero Rect and inverted rectangles, whose minimum coordinates are
+// greater than the maximum ones, are ignored. Rectangles with zero
+// width or height, like the bounds of a horizontal segment, are not.
+func (r Rect) Union(s Rect) Rect {
+	if r.isNull() {
+		return s
+	}
+	if s.isNull() {
+		return r
+	}
+	return Rect{
+		float32(math.Min(float64(r.MinX), float64(s.MinX))),
+		float32(math.Min(float64(r.MinY), float64(s.MinY))),
+		float32(math.Max(float64(r.MaxX), float64(s.MaxX))),
+		float32(math.Max(float64(r.MaxY), float64(s.MaxY))),
+	}
+}
+
+// isNull reports whether r is the zero Rect or is inverted.
+func (r Rect) isNull() bool {
+	return r == Rect{} || r.MinX > r.MaxX || r.MinY > r.MaxY
+}
+
+// Intersect returns the largest rectangle contained by both r and s.
+// If the two rectangles don't overlap the zero Rect is returned.
+func (r Rect) Intersect(s Rect) Rect {
+	i := Rect{
+		float32(math.Max(float64(r.MinX), float64(s.MinX))),
+		float32(math.Max(float64(r.MinY), float64(s.MinY))),
+		float32(math.Min(float64(r.MaxX), float64(s.MaxX))),
+		float32(math.Min(float64(r.MaxY), float64(s.MaxY))),
+	}
+	if i.Empty() {
+		return Rect{}
+	}
+	return i
+}
+
+// Overlaps reports whether r and s have any point in common,
+// including points on their borders.
+func (r Rect) Overlaps(s Rect) bool {
+	return r.MinX <= s.MaxX && s.MinX <= r.MaxX && r.MinY <= s.MaxY && s.MinY <= r.MaxY
+}
+
+// Inset returns r shrunk by n on each side. A negative n grows the
+// rectangle. If r is smaller than 2*n along an axis, the result is
+// collapsed to the center of r along that axis.
+func (r Rect) Inset(n float32) Rect {
+	if r.Dx() < 2*n {
+		r.MinX = (r.MinX + r.MaxX) / 2
+		r.MaxX = r.MinX
+	} else {
+		r.MinX, r.MaxX = r.MinX+n, r.MaxX-n
+	}
+	if r.Dy() < 2*n {
+		r.MinY = (r.MinY + r.MaxY) / 2
+		r.MaxY = r.MinY
+	} else {
+		r.MinY, r.MaxY = r.MinY+n, r.MaxY-n
+	}
+	return r
+}
+
+// OBB is an oriented bounding box, that is a rectangle rotated
+// around its center.
+type OBB struct {
+	// Center of the box
+	X, Y float32
+
+	// Half of the size of the box along its axes
+	HalfWidth, HalfHeight float32
+
+	// Angle in degrees between the x axis and the first axis of
+	// the box
+	Angle float32
+}
+
+// Corners returns the coordinates of the four corners of the box,
+// counterclockwise from the bottom-left one in the box orientation.
+func (o OBB) Corners() []float32 {
+	s, c := math.Sincos(radians(o.Angle))
+	ux, uy := float32(c)*o.HalfWidth, float32(s)*o.HalfWidth
+	vx, vy := -float32(s)*o.HalfHeight, float32(c)*o.HalfHeight
+	return []float32{
+		o.X - ux - vx, o.Y - uy - vy,
+		o.X + ux - vx, o.Y + uy - vy,
+		o.X + ux + vx, o.Y + uy + vy,
+		o.X - ux + vx, o.Y - uy + vy,
+	}
+}
+
+// orientedBounds returns the smallest box enclosing the given points
+// whose first axis is the x axis transformed by m.
+func orientedBounds(points []float32, m mathgl.Mat4f) OBB {
+	angle := math.Atan2(float64(m[1]), float64(m[0]))
+	if m[0] == 0 && m[1] == 0 {
+		angle = 0
+	}
+	s, c := math.Sincos(angle)
+	ux, uy := float32(c), float32(s)
+
+	// Project the points on the axes of the box
+	projected := make([]float32, len(points))
+	for i := 0; i+1 < len(points); i += 2 {
+		x, y := points[i], points[i+1]
+		projected[i] = x*ux + y*uy
+		projected[i+1] = -x*uy + y*ux
+	}
+	minU, minV, maxU, maxV := boundsOf(projected)
+	cu, cv := (minU+maxU)/2, (minV+maxV)/2
+
+	return OBB{
+		X:          cu*ux - cv*uy,
+		Y:          cu*uy + cv*ux,
+		HalfWidth:  (maxU - minU) / 2,
+		HalfHeight: (maxV - minV) / 2,
+		Angle:      float32(angle * 180 / math.Pi),
+	}
+}
+
+// appendTransformed appends to dst the given points transformed by
+// m.
+func appendTransformed(dst []float32, points []float32, m mathgl.Mat4f) []float32 {
+	for i := 0; i+1 < len(points); i += 2 {
+		x, y := transformPoint(m, points[i], points[i+1])
+		dst = append(dst, x, y)
+	}
+	return dst
+}
+
+// rectOf returns the rectangle enclosing the given points.
+func rectOf(points []float32) Rect {
+	minX, minY, maxX, maxY := boundsOf(points)
+	return Rect{minX, minY, maxX, maxY}
+}
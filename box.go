@@ -1,9 +1,6 @@
 package shapes
 
 import (
-	"image"
-
-	"github.com/remogatto/mathgl"
 	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shaders"
 )
@@ -65,67 +62,45 @@ func NewBox(program shaders.Program, width, height float32) *Box {
 	// Set the default color
 	box.SetColor(DefaultColor)
 
-	box.program = program
-	box.program.Use()
-
-	// Get variables IDs from shaders
-	box.posId = program.GetAttribute("pos")
-	box.colorId = program.GetAttribute("color")
-	box.projMatrixId = program.GetUniform("projection")
-	box.modelMatrixId = program.GetUniform("model")
-	box.viewMatrixId = program.GetUniform("view")
-	box.texInId = program.GetAttribute("texIn")
-	box.textureId = program.GetUniform("texture")
-	box.texRatioId = program.GetUniform("texRatio")
+	box.initTexturedProgram(program)
 
-	// Fill the model matrix with the identity.
-	box.modelMatrix = mathgl.Ident4f()
+	// Start with no rotation and unit scale.
+	box.initTransform()
 
 	// Create the bounding rectangle for the shape.
-	box.bounds = image.Rect(
-		int(-width/2), int(-height/2),
-		int(width/2), int(height/2),
-	)
+	box.setLocalBounds(-width/2, -height/2, width/2, height/2)
 
 	return box
 }
 
-// Draw actually renders the shape on the surface.
-func (box *Box) Draw() {
-	box.program.Use()
-
-	gl.VertexAttribPointer(box.posId, 2, gl.FLOAT, false, 0, &box.vertices[0])
-	gl.EnableVertexAttribArray(box.posId)
-
-	gl.VertexAttribPointer(box.colorId, 4, gl.FLOAT, false, 0, &box.vColor[0])
-	gl.EnableVertexAttribArray(box.colorId)
-
-	gl.UniformMatrix4fv(int32(box.modelMatrixId), 1, false, (*float32)(&box.modelMatrix[0]))
-	gl.UniformMatrix4fv(int32(box.projMatrixId), 1, false, (*float32)(&box.projMatrix[0]))
-	gl.UniformMatrix4fv(int32(box.viewMatrixId), 1, false, (*float32)(&box.viewMatrix[0]))
-
-	gl.Uniform1f(int32(box.texRatioId), 0.0)
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the box.
+func (box *Box) Contains(x, y float32) bool {
+	lx, ly, ok := box.toLocal(x, y)
+	return ok && box.localBounds.Contains(lx, ly)
+}
 
-	// Texture
-	if len(box.texCoords) > 0 {
-		gl.Uniform1f(int32(box.texRatioId), 1.0)
-		gl.VertexAttribPointer(box.texInId, 2, gl.FLOAT, false, 0, &box.texCoords[0])
-		gl.EnableVertexAttribArray(box.texInId)
-		gl.ActiveTexture(gl.TEXTURE0)
-		gl.BindTexture(gl.TEXTURE_2D, box.texBuffer)
-		gl.Uniform1i(int32(box.textureId), 0)
-	}
+// hulls returns the box as a single convex piece.
+func (box *Box) hulls() []hull {
+	return box.boundsHull()
+}
 
-	gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the box within
+// maxDist.
+func (box *Box) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(box, originX, originY, dirX, dirY, maxDist)
+}
 
-	gl.Flush()
-	gl.Finish()
+// Draw actually renders the shape on the surface.
+func (box *Box) Draw() {
+	box.drawArrays(gl.TRIANGLE_STRIP)
 }
 
 // Clone makes a copy of the shape.
 func (box *Box) Clone() Shape {
-	b := NewBox(box.program, float32(box.bounds.Dx()), float32(box.bounds.Dy()))
-	b.SetColor(box.color)
+	b := NewBox(box.program, box.localBounds.Dx(), box.localBounds.Dy())
+	b.copyColors(&box.Base)
 	b.SetTexture(box.texBuffer, box.texCoords)
 	return b
 }
@@ -0,0 +1,72 @@
+package shapes
+
+import gl "github.com/remogatto/opengles2"
+
+// Vertex buffer objects of a shape
+const (
+	positionBuffer = iota
+	colorBuffer
+	texCoordBuffer
+	bufferCount
+)
+
+// vertexBuffers holds the vertex buffer objects storing the
+// positions, the colors and the texture coordinates of a shape on the
+// GPU. Buffers are created and filled when the shape is drawn the
+// first time and uploaded again only when marked as stale.
+type vertexBuffers struct {
+	// IDs returned by the OpenGL context, zero if not created
+	ids [bufferCount]uint32
+
+	// Number of floats uploaded in each buffer
+	sizes [bufferCount]int
+
+	// Whether each buffer must be uploaded again
+	stale [bufferCount]bool
+
+	// Whether the data changes at every frame
+	streamed bool
+}
+
+// markStale marks the given buffer to be uploaded again before the
+// next draw.
+func (vb *vertexBuffers) markStale(buffer int) {
+	vb.stale[buffer] = true
+}
+
+// bindBuffer uploads data in the given buffer, if needed, and binds
+// it to the attribute id, whose elements are made of size floats.
+func (vb *vertexBuffers) bindBuffer(buffer int, data []float32, id uint32, size int32) {
+	if len(data) == 0 {
+		return
+	}
+	if vb.ids[buffer] == 0 {
+		gl.GenBuffers(1, &vb.ids[buffer])
+		vb.stale[buffer] = true
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, vb.ids[buffer])
+	if vb.stale[buffer] || vb.sizes[buffer] != len(data) {
+		usage := gl.Enum(gl.STATIC_DRAW)
+		if vb.streamed {
+			usage = gl.STREAM_DRAW
+		}
+		gl.BufferData(gl.ARRAY_BUFFER, gl.Sizeiptr(len(data)*4), gl.Void(&data[0]), usage)
+		vb.sizes[buffer] = len(data)
+		vb.stale[buffer] = false
+	}
+	gl.VertexAttribPointer(id, size, gl.FLOAT, false, 0, gl.Void(nil))
+	gl.EnableVertexAttribArray(id)
+}
+
+// Release frees the buffers on the GPU. They are created again if the
+// shape is drawn afterwards. It must be called from the goroutine
+// owning the OpenGL context.
+func (vb *vertexBuffers) Release() {
+	for i, id := range vb.ids {
+		if id != 0 {
+			gl.DeleteBuffers(1, &vb.ids[i])
+			vb.ids[i] = 0
+			vb.sizes[i] = 0
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+var (
+	// DefaultCircleVS is a default vertex shader for circles. It's
+	// the same as DefaultBoxVS.
+	DefaultCircleVS = DefaultBoxVS
+
+	// DefaultCircleFS is a default fragment shader for circles.
+	// It's the same as DefaultBoxFS.
+	DefaultCircleFS = DefaultBoxFS
+)
+
+// Circle represents a circle shape.
+type Circle struct {
+	Base
+
+	// Radius of the circle
+	radius float32
+
+	// Number of segments approximating the circumference
+	segments int
+}
+
+// NewCircle creates a new circle. It takes as arguments a linked
+// program, the radius and the number of segments used to approximate
+// the circumference. The number of segments can't be less than 3.
+func NewCircle(program shaders.Program, radius float32, segments int) *Circle {
+
+	circle := new(Circle)
+
+	if segments < 3 {
+		segments = 3
+	}
+
+	circle.radius = radius
+	circle.segments = segments
+
+	// The circle is built as a triangle fan around its center at
+	// (0, 0).
+	circle.vertices = ellipseFan(make([]float32, 0, (segments+2)*2), radius, radius, 0, 2*math.Pi, segments)
+
+	// Set the default color
+	circle.SetColor(DefaultColor)
+
+	circle.initTexturedProgram(program)
+
+	// Start with no rotation and unit scale.
+	circle.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	circle.setLocalBounds(-radius, -radius, radius, radius)
+
+	return circle
+}
+
+// Radius returns the radius of the circle.
+func (circle *Circle) Radius() float32 {
+	return circle.radius
+}
+
+// SetTexture sets a texture for the circle. If texCoords is nil the
+// texture coordinates are generated so that the whole texture is
+// mapped on the square enclosing the circle.
+func (circle *Circle) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = circle.boundsTexCoords()
+	}
+	return circle.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the circle.
+func (circle *Circle) Contains(x, y float32) bool {
+	lx, ly, ok := circle.toLocal(x, y)
+	return ok && lx*lx+ly*ly <= circle.radius*circle.radius
+}
+
+// hulls returns the circle as a single convex piece. If the circle is
+// not uniformly scaled, its outline is used instead.
+func (circle *Circle) hulls() []hull {
+	if h, ok := circle.circleHull(circle.radius); ok {
+		return h
+	}
+	return circle.outlineHull(circle.vertices[2:])
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the circle within
+// maxDist.
+func (circle *Circle) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(circle, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the circle on the surface.
+func (circle *Circle) Draw() {
+	circle.drawArrays(gl.TRIANGLE_FAN)
+}
+
+// Clone makes a copy of the circle.
+func (circle *Circle) Clone() Shape {
+	c := NewCircle(circle.program, circle.radius, circle.segments)
+	c.copyColors(&circle.Base)
+	if len(circle.texCoords) > 0 {
+		c.SetTexture(circle.texBuffer, circle.texCoords)
+	}
+	return c
+}
@@ -0,0 +1,267 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+)
+
+// Manifold describes how two colliding shapes overlap.
+type Manifold struct {
+	// Normal is the unit vector along which the second shape must
+	// be moved to separate it from the first one.
+	Normal [2]float32
+
+	// Depth is the distance the second shape must be moved along
+	// Normal to separate the shapes.
+	Depth float32
+}
+
+// hull is a convex piece of a shape in world coordinates. It's a
+// polygon, a segment if it has two points or a circle centered in
+// its only point if radius is greater than zero.
+type hull struct {
+	points []float32
+	radius float32
+}
+
+// collider is implemented by the shapes that can be split in convex
+// hulls.
+type collider interface {
+	hulls() []hull
+}
+
+// Intersects returns true if the shapes a and b overlap.
+func Intersects(a, b Shape) bool {
+	_, ok := Collide(a, b)
+	return ok
+}
+
+// Collide tests the shapes a and b for collision using the Separating
+// Axis Theorem on their transformed vertices. Shapes are split in
+// convex pieces, so that concave polygons and strokes are supported
+// too. If the shapes overlap it returns true and the manifold of the
+// deepest overlap between their pieces.
+func Collide(a, b Shape) (Manifold, bool) {
+	var (
+		m     Manifold
+		found bool
+	)
+	for _, ha := range hullsOf(a) {
+		for _, hb := range hullsOf(b) {
+			if mh, ok := collideHulls(ha, hb); ok && (!found || mh.Depth > m.Depth) {
+				m, found = mh, true
+			}
+		}
+	}
+	return m, found
+}
+
+// hullsOf returns the convex pieces of s. Shapes not implementing
+// collider are approximated by their axis-aligned bounding box.
+func hullsOf(s Shape) []hull {
+	if c, ok := s.(collider); ok {
+		return c.hulls()
+	}
+	r := s.AABB()
+	return []hull{{points: []float32{r.MinX, r.MinY, r.MaxX, r.MinY, r.MaxX, r.MaxY, r.MinX, r.MaxY}}}
+}
+
+// collideHulls tests two convex pieces for collision.
+func collideHulls(a, b hull) (Manifold, bool) {
+	if a.radius > 0 && b.radius > 0 {
+		return collideCircles(a, b)
+	}
+
+	// Candidate separating axes are the normals of the edges of
+	// the polygons, the directions of the segments and, for
+	// circles, the direction to the nearest vertex of the other
+	// piece.
+	axes := appendAxes(nil, a)
+	axes = appendAxes(axes, b)
+	if a.radius > 0 {
+		axes = appendCircleAxis(axes, a, b)
+	}
+	if b.radius > 0 {
+		axes = appendCircleAxis(axes, b, a)
+	}
+
+	m := Manifold{Depth: float32(math.Inf(1))}
+	for i := 0; i+1 < len(axes); i += 2 {
+		nx, ny := axes[i], axes[i+1]
+		minA, maxA := a.project(nx, ny)
+		minB, maxB := b.project(nx, ny)
+		if maxA < minB || maxB < minA {
+			return Manifold{}, false
+		}
+		// Push b towards the side of the shortest way out
+		if d := maxA - minB; d < m.Depth {
+			m = Manifold{[2]float32{nx, ny}, d}
+		}
+		if d := maxB - minA; d < m.Depth {
+			m = Manifold{[2]float32{-nx, -ny}, d}
+		}
+	}
+	if len(axes) == 0 {
+		return Manifold{}, false
+	}
+	return m, true
+}
+
+// collideCircles tests two circles for collision.
+func collideCircles(a, b hull) (Manifold, bool) {
+	dx, dy := b.points[0]-a.points[0], b.points[1]-a.points[1]
+	d := float32(math.Hypot(float64(dx), float64(dy)))
+	depth := a.radius + b.radius - d
+	if depth < 0 {
+		return Manifold{}, false
+	}
+	if d == 0 {
+		return Manifold{[2]float32{1, 0}, depth}, true
+	}
+	return Manifold{[2]float32{dx / d, dy / d}, depth}, true
+}
+
+// appendAxes appends to axes the unit normals of the edges of h. A
+// segment contributes its direction too.
+func appendAxes(axes []float32, h hull) []float32 {
+	n := len(h.points) / 2
+	if n < 2 {
+		return axes
+	}
+	edges := n
+	if n == 2 {
+		edges = 1
+	}
+	for i := 0; i < edges; i++ {
+		j := (i + 1) % n
+		dx, dy := h.points[j*2]-h.points[i*2], h.points[j*2+1]-h.points[i*2+1]
+		l := float32(math.Hypot(float64(dx), float64(dy)))
+		if l == 0 {
+			continue
+		}
+		axes = append(axes, -dy/l, dx/l)
+		if n == 2 {
+			axes = append(axes, dx/l, dy/l)
+		}
+	}
+	return axes
+}
+
+// appendCircleAxis appends to axes the direction from the center of
+// the circle c to the nearest point of h.
+func appendCircleAxis(axes []float32, c, h hull) []float32 {
+	cx, cy := c.points[0], c.points[1]
+	best := math.Inf(1)
+	var ax, ay float32
+	for i := 0; i+1 < len(h.points); i += 2 {
+		dx, dy := h.points[i]-cx, h.points[i+1]-cy
+		if d := math.Hypot(float64(dx), float64(dy)); d < best && d > 0 {
+			best, ax, ay = d, dx/float32(d), dy/float32(d)
+		}
+	}
+	if math.IsInf(best, 1) {
+		return axes
+	}
+	return append(axes, ax, ay)
+}
+
+// project returns the interval covered by h projected on the axis
+// (nx, ny).
+func (h hull) project(nx, ny float32) (float32, float32) {
+	if h.radius > 0 {
+		c := h.points[0]*nx + h.points[1]*ny
+		return c - h.radius, c + h.radius
+	}
+	min, max := float32(math.Inf(1)), float32(math.Inf(-1))
+	for i := 0; i+1 < len(h.points); i += 2 {
+		p := h.points[i]*nx + h.points[i+1]*ny
+		if p < min {
+			min = p
+		}
+		if p > max {
+			max = p
+		}
+	}
+	return min, max
+}
+
+// outlineHull returns the hull of a convex shape whose outline is
+// described by the given local points.
+func (b *Base) outlineHull(outline []float32) []hull {
+	return []hull{{points: appendTransformed(nil, outline, b.worldMatrix())}}
+}
+
+// circleHull returns the hull of a circle with the given radius
+// centered at the local origin. It returns false if the shape is
+// skewed or not uniformly scaled, so that it's not a circle anymore.
+func (b *Base) circleHull(radius float32) ([]hull, bool) {
+	m := b.worldMatrix()
+	sx := math.Hypot(float64(m[0]), float64(m[1]))
+	sy := math.Hypot(float64(m[4]), float64(m[5]))
+	dot := float64(m[0]*m[4] + m[1]*m[5])
+	if math.Abs(sx-sy) > 1e-4*sx || math.Abs(dot) > 1e-4*sx*sy {
+		return nil, false
+	}
+	x, y := transformPoint(m, 0, 0)
+	return []hull{{points: []float32{x, y}, radius: radius * float32(sx)}}, true
+}
+
+// boundsHull returns the hull of the rectangle enclosing the
+// untransformed shape.
+func (b *Base) boundsHull() []hull {
+	r := b.localBounds
+	return b.outlineHull([]float32{r.MinX, r.MinY, r.MaxX, r.MinY, r.MaxX, r.MaxY, r.MinX, r.MaxY})
+}
+
+// triangleHulls returns the triangles described by the vertices of
+// the shape in the given primitive mode as hulls. Triangles are
+// listed by indices if not nil. Degenerate triangles, like the ones
+// joining the pieces of a strip, are skipped.
+func (b *Base) triangleHulls(mode gl.Enum, indices []uint16) []hull {
+	world := appendTransformed(nil, b.vertices, b.worldMatrix())
+	var hulls []hull
+	forEachTriangle(len(world)/2, mode, indices, func(i, j, k int) bool {
+		if cross(world[i*2], world[i*2+1], world[j*2], world[j*2+1], world[k*2], world[k*2+1]) == 0 {
+			return false
+		}
+		hulls = append(hulls, hull{points: []float32{
+			world[i*2], world[i*2+1],
+			world[j*2], world[j*2+1],
+			world[k*2], world[k*2+1],
+		}})
+		return false
+	})
+	return hulls
+}
+
+// lineHulls returns the segments of the polyline described by the
+// given local points as hulls.
+func (b *Base) lineHulls(points []float32) []hull {
+	world := appendTransformed(nil, points, b.worldMatrix())
+	var hulls []hull
+	for i := 0; i+3 < len(world); i += 2 {
+		hulls = append(hulls, hull{points: world[i : i+4 : i+4]})
+	}
+	return hulls
+}
+
+// isConvex returns true if the closed polyline described by points
+// is convex.
+func isConvex(points []float32) bool {
+	n := len(points) / 2
+	sign := 0
+	for i := 0; i < n; i++ {
+		j, k := (i+1)%n, (i+2)%n
+		c := cross(points[i*2], points[i*2+1], points[j*2], points[j*2+1], points[k*2], points[k*2+1])
+		switch {
+		case c > 0 && sign < 0, c < 0 && sign > 0:
+			return false
+		case c > 0:
+			sign = 1
+		case c < 0:
+			sign = -1
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+)
+
+var (
+	// DefaultHitTolerance is the maximum distance in pixels
+	// between a point and a line, such as a thin segment or curve,
+	// for the point to be considered on the line.
+	DefaultHitTolerance float32 = 2
+)
+
+// containsLocal returns true if (x, y), in world coordinates, lies
+// in one of the triangles described by the vertices of the shape in
+// the given primitive mode. Triangles are listed by indices if not
+// nil.
+func (b *Base) containsLocal(mode gl.Enum, indices []uint16, x, y float32) bool {
+	lx, ly, ok := b.toLocal(x, y)
+	return ok && inPrimitives(b.vertices, mode, indices, lx, ly)
+}
+
+// nearLine returns true if (x, y), in world coordinates, lies within
+// DefaultHitTolerance from the polyline described by the given local
+// points.
+func (b *Base) nearLine(points []float32, x, y float32) bool {
+	world := appendTransformed(nil, points, b.worldMatrix())
+	return distanceToPolyline(world, x, y) <= DefaultHitTolerance
+}
+
+// inPrimitives returns true if (x, y) lies in one of the triangles
+// described by vertices in the given primitive mode. Triangles are
+// listed by indices if not nil. Other primitives contain no points.
+func inPrimitives(vertices []float32, mode gl.Enum, indices []uint16, x, y float32) bool {
+	return forEachTriangle(len(vertices)/2, mode, indices, func(i, j, k int) bool {
+		ax, ay := vertices[i*2], vertices[i*2+1]
+		bx, by := vertices[j*2], vertices[j*2+1]
+		cx, cy := vertices[k*2], vertices[k*2+1]
+		return inTriangle(x, y, ax, ay, bx, by, cx, cy) || inTriangle(x, y, ax, ay, cx, cy, bx, by)
+	})
+}
+
+// forEachTriangle calls f with the indices of the vertices of each
+// triangle described by n vertices in the given primitive mode,
+// listed by indices if not nil. It stops as soon as f returns true
+// and returns true in that case. Primitives other than triangles are
+// ignored.
+func forEachTriangle(n int, mode gl.Enum, indices []uint16, f func(i, j, k int) bool) bool {
+	vertex := func(i int) int {
+		if indices != nil {
+			return int(indices[i])
+		}
+		return i
+	}
+	if indices != nil {
+		n = len(indices)
+	}
+	switch mode {
+	case gl.TRIANGLES:
+		for i := 0; i+2 < n; i += 3 {
+			if f(vertex(i), vertex(i+1), vertex(i+2)) {
+				return true
+			}
+		}
+	case gl.TRIANGLE_STRIP:
+		for i := 0; i+2 < n; i++ {
+			if f(vertex(i), vertex(i+1), vertex(i+2)) {
+				return true
+			}
+		}
+	case gl.TRIANGLE_FAN:
+		for i := 1; i+1 < n; i++ {
+			if f(vertex(0), vertex(i), vertex(i+1)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// winding returns the number of times the closed polyline described
+// by points winds around (x, y) counterclockwise.
+func winding(points []float32, x, y float32) int {
+	w := 0
+	n := len(points) / 2
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		ax, ay := points[i*2], points[i*2+1]
+		bx, by := points[j*2], points[j*2+1]
+		if ay <= y {
+			if by > y && cross(ax, ay, bx, by, x, y) > 0 {
+				w++
+			}
+		} else if by <= y && cross(ax, ay, bx, by, x, y) < 0 {
+			w--
+		}
+	}
+	return w
+}
+
+// distanceToPolyline returns the distance between (x, y) and the
+// nearest segment of the polyline described by points.
+func distanceToPolyline(points []float32, x, y float32) float32 {
+	d := math.Inf(1)
+	if len(points) == 2 {
+		d = math.Hypot(float64(x-points[0]), float64(y-points[1]))
+	}
+	for i := 0; i+3 < len(points); i += 2 {
+		d = math.Min(d, distanceToSegment(points[i], points[i+1], points[i+2], points[i+3], x, y))
+	}
+	return float32(d)
+}
+
+// distanceToSegment returns the distance between (x, y) and the
+// segment from (ax, ay) to (bx, by).
+func distanceToSegment(ax, ay, bx, by, x, y float32) float64 {
+	dx, dy := float64(bx-ax), float64(by-ay)
+	px, py := float64(x-ax), float64(y-ay)
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, (px*dx+py*dy)/l))
+	}
+	return math.Hypot(px-t*dx, py-t*dy)
+}
@@ -0,0 +1,129 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Ellipse represents an ellipse shape.
+type Ellipse struct {
+	Base
+
+	// Radii of the ellipse
+	rx, ry float32
+
+	// Number of segments approximating the outline
+	segments int
+}
+
+// NewEllipse creates a new ellipse. It takes as arguments a linked
+// program (e.g. built from DefaultCircleVS and DefaultCircleFS), the
+// horizontal and vertical radii and the number of segments used to
+// approximate the outline. The number of segments can't be less
+// than 3.
+func NewEllipse(program shaders.Program, rx, ry float32, segments int) *Ellipse {
+
+	ellipse := new(Ellipse)
+
+	if segments < 3 {
+		segments = 3
+	}
+
+	ellipse.rx, ellipse.ry = rx, ry
+	ellipse.segments = segments
+
+	// The ellipse is built as a triangle fan around its center
+	// at (0, 0).
+	ellipse.vertices = ellipseFan(make([]float32, 0, (segments+2)*2), rx, ry, 0, 2*math.Pi, segments)
+
+	// Set the default color
+	ellipse.SetColor(DefaultColor)
+
+	ellipse.initTexturedProgram(program)
+
+	// Start with no rotation and unit scale.
+	ellipse.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	ellipse.setLocalBounds(-rx, -ry, rx, ry)
+
+	return ellipse
+}
+
+// Radii returns the horizontal and vertical radii of the ellipse.
+func (ellipse *Ellipse) Radii() (float32, float32) {
+	return ellipse.rx, ellipse.ry
+}
+
+// SetTexture sets a texture for the ellipse. If texCoords is nil the
+// texture coordinates are generated so that the whole texture is
+// mapped on the rectangle enclosing the ellipse.
+func (ellipse *Ellipse) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = ellipse.boundsTexCoords()
+	}
+	return ellipse.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the ellipse.
+func (ellipse *Ellipse) Contains(x, y float32) bool {
+	lx, ly, ok := ellipse.toLocal(x, y)
+	if !ok || ellipse.rx == 0 || ellipse.ry == 0 {
+		return false
+	}
+	nx, ny := lx/ellipse.rx, ly/ellipse.ry
+	return nx*nx+ny*ny <= 1
+}
+
+// hulls returns the outline of the ellipse as a single convex piece.
+func (ellipse *Ellipse) hulls() []hull {
+	return ellipse.outlineHull(ellipse.vertices[2:])
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the ellipse within
+// maxDist.
+func (ellipse *Ellipse) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(ellipse, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the ellipse on the surface.
+func (ellipse *Ellipse) Draw() {
+	ellipse.drawArrays(gl.TRIANGLE_FAN)
+}
+
+// Clone makes a copy of the ellipse.
+func (ellipse *Ellipse) Clone() Shape {
+	e := NewEllipse(ellipse.program, ellipse.rx, ellipse.ry, ellipse.segments)
+	e.copyColors(&ellipse.Base)
+	if len(ellipse.texCoords) > 0 {
+		e.SetTexture(ellipse.texBuffer, ellipse.texCoords)
+	}
+	return e
+}
+
+// ellipseFan appends to vertices a triangle fan approximating the
+// elliptic sector centered at (0, 0) with radii rx and ry, from angle
+// start sweeping the given angle. Angles are in radians. The fan
+// consists of the center followed by segments+1 points of the
+// outline.
+func ellipseFan(vertices []float32, rx, ry float32, start, sweep float64, segments int) []float32 {
+	vertices = append(vertices, 0, 0)
+	for i := 0; i <= segments; i++ {
+		a := start + sweep*float64(i)/float64(segments)
+		vertices = append(
+			vertices,
+			rx*float32(math.Cos(a)),
+			ry*float32(math.Sin(a)),
+		)
+	}
+	return vertices
+}
+
+// radians converts the given angle from degrees to radians.
+func radians(angle float32) float64 {
+	return float64(angle) * math.Pi / 180
+}
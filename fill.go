@@ -0,0 +1,164 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+)
+
+// FillRule is the rule used to decide which parts of a path are
+// inside it.
+type FillRule int
+
+const (
+	// NonZero fills the regions around which the path winds a
+	// non-zero number of times.
+	NonZero FillRule = iota
+
+	// EvenOdd fills the regions crossed an odd number of times by
+	// a ray starting from them.
+	EvenOdd
+)
+
+// inside returns true if the given winding number identifies a
+// filled region.
+func (rule FillRule) inside(winding int) bool {
+	if rule == EvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// fillEdge is a non-horizontal edge of a path, oriented bottom-up.
+type fillEdge struct {
+	x0, y0, x1, y1 float64
+
+	// dir is +1 if the original edge was going up, -1 otherwise
+	dir int
+}
+
+// xAt returns the x coordinate of the edge at the given y.
+func (e fillEdge) xAt(y float64) float64 {
+	return e.x0 + (e.x1-e.x0)*(y-e.y0)/(e.y1-e.y0)
+}
+
+// fillCrossing is an edge crossing a horizontal slab.
+type fillCrossing struct {
+	// x coordinates at the bottom, the top and the middle of the
+	// slab
+	xa, xb, xm float64
+	dir        int
+}
+
+// fill tessellates the region enclosed by the given closed
+// polylines, according to the fill rule. Polylines can intersect
+// each other or themselves, so holes are obtained with inner
+// polylines. It returns the vertices of the triangles covering the
+// region.
+//
+// The region is split in horizontal slabs at every vertex and
+// intersection. Within a slab edges don't cross, so the filled spans
+// between them are trapezoids.
+func fill(polylines [][]float32, rule FillRule) []float32 {
+	var (
+		edges []fillEdge
+		ys    []float64
+	)
+
+	for _, p := range polylines {
+		n := len(p) / 2
+		if n < 3 {
+			continue
+		}
+		for i := 0; i < n; i++ {
+			j := (i + 1) % n
+			e := fillEdge{
+				float64(p[i*2]), float64(p[i*2+1]),
+				float64(p[j*2]), float64(p[j*2+1]),
+				1,
+			}
+			ys = append(ys, e.y0)
+			if e.y0 == e.y1 {
+				continue
+			}
+			if e.y0 > e.y1 {
+				e.x0, e.y0, e.x1, e.y1 = e.x1, e.y1, e.x0, e.y0
+				e.dir = -1
+			}
+			edges = append(edges, e)
+		}
+	}
+
+	// Split slabs at edge intersections too
+	for i := range edges {
+		for j := i + 1; j < len(edges); j++ {
+			if y, ok := intersectionY(edges[i], edges[j]); ok {
+				ys = append(ys, y)
+			}
+		}
+	}
+	sort.Float64s(ys)
+
+	var (
+		triangles []float32
+		active    []fillCrossing
+	)
+	for k := 0; k+1 < len(ys); k++ {
+		ya, yb := ys[k], ys[k+1]
+		if yb-ya < 1e-9 {
+			continue
+		}
+		ym := (ya + yb) / 2
+
+		active = active[:0]
+		for _, e := range edges {
+			if e.y0 < ym && e.y1 > ym {
+				active = append(active, fillCrossing{e.xAt(ya), e.xAt(yb), e.xAt(ym), e.dir})
+			}
+		}
+		sort.Slice(active, func(i, j int) bool { return active[i].xm < active[j].xm })
+
+		winding, start := 0, 0
+		for i, c := range active {
+			wasInside := rule.inside(winding)
+			winding += c.dir
+			isInside := rule.inside(winding)
+			switch {
+			case !wasInside && isInside:
+				start = i
+			case wasInside && !isInside:
+				l := active[start]
+				triangles = append(
+					triangles,
+					float32(l.xa), float32(ya),
+					float32(c.xa), float32(ya),
+					float32(c.xb), float32(yb),
+					float32(l.xa), float32(ya),
+					float32(c.xb), float32(yb),
+					float32(l.xb), float32(yb),
+				)
+			}
+		}
+	}
+
+	return triangles
+}
+
+// intersectionY returns the y coordinate of the intersection point
+// between two edges, if they cross in their interiors.
+func intersectionY(a, b fillEdge) (float64, bool) {
+	if a.y1 <= b.y0 || b.y1 <= a.y0 {
+		return 0, false
+	}
+	dxa, dya := a.x1-a.x0, a.y1-a.y0
+	dxb, dyb := b.x1-b.x0, b.y1-b.y0
+	d := dxa*dyb - dya*dxb
+	if math.Abs(d) < 1e-12 {
+		return 0, false
+	}
+	t := ((b.x0-a.x0)*dyb - (b.y0-a.y0)*dxb) / d
+	u := ((b.x0-a.x0)*dya - (b.y0-a.y0)*dxa) / d
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return 0, false
+	}
+	return a.y0 + t*dya, true
+}
@@ -1,50 +1,75 @@
 package shapes
 
 import (
-	"image"
+	"sort"
 	"sync"
+
+	"github.com/remogatto/mathgl"
 )
 
 // Group is a structure for grouping shapes. It implements Shape.
+// The group has its own position, rotation and scale, which are
+// applied on top of the ones of its children: the coordinates of
+// the children are relative to the group.
 type Group struct {
-	// Center of the group
-	x, y float32
-
-	// Angle
-	angle float32
-
-	// Bounds of the group
-	bounds image.Rectangle
+	// Position, rotation and scale
+	transform
 
 	// rwMutex handle councurrent access to children slice
 	rwMutex sync.RWMutex
 
 	// children is the slice containing the shapes of the group
 	children []Shape
+
+	// renderer draws the children outside a frame
+	renderer *Renderer
 }
 
 // NewGroup instantiates a group object.
 func NewGroup() *Group {
-	return &Group{
+	g := &Group{
 		children: make([]Shape, 0),
 	}
+	g.initTransform()
+	return g
+}
+
+// child is implemented by the shapes whose transform can be
+// combined with the one of the group containing them.
+type child interface {
+	// setParent sets the transform of the group containing the
+	// shape.
+	setParent(parent *transform)
+
+	// appendPoints appends to points the vertices of the shape
+	// transformed by its model matrix and then by m.
+	appendPoints(points []float32, m mathgl.Mat4f) []float32
 }
 
 // Append appends a shape to the group.
 func (g *Group) Append(s Shape) {
 	g.rwMutex.Lock()
-	defer g.rwMutex.Unlock()
-
 	g.children = append(g.children, s)
+	cx, cy := g.childrenBounds().Center()
+	g.rwMutex.Unlock()
+
+	// Unless a pivot was set, rotate and scale the group around
+	// the center of its children.
+	g.centerPivot(cx, cy)
 
-	if len(g.children) == 1 {
-		g.bounds = s.Bounds()
+	// Observers may query the group, so they are notified after
+	// releasing the lock.
+	if c, ok := s.(child); ok {
+		c.setParent(&g.transform)
 	} else {
-		g.bounds = g.bounds.Union(s.Bounds())
+		g.boundsChanged()
 	}
+}
 
-	g.x = float32((g.bounds.Min.X + g.bounds.Max.X) / 2)
-	g.y = float32((g.bounds.Min.Y + g.bounds.Max.Y) / 2)
+// childrenBounds returns the rectangle enclosing the children, in
+// the coordinates of the group.
+func (g *Group) childrenBounds() Rect {
+	return rectOf(g.appendChildrenPoints(nil, mathgl.Ident4f()))
 }
 
 // func (g *Group) Remove(k string) error {
@@ -67,8 +92,22 @@ func (g *Group) GetAt(id int) Shape {
 	return g.children[id]
 }
 
-// Draw draws all the shapes in the group calling their Draw method.
+// Draw draws all the shapes in the group. The transform of the group
+// is combined with the ones of its children. Between Renderer.Begin
+// and Renderer.End the children are enqueued in the frame, otherwise
+// the group renders them in a frame of its own: in both cases they
+// are sorted by layer (see SetZ) and consecutive children sharing
+// program, texture and matrices, including the ones of nested groups,
+// are rendered with a single draw call. Overlapping children in the
+// same layer are drawn in the order they were appended.
 func (g *Group) Draw() {
+	if current == nil {
+		if g.renderer == nil {
+			g.renderer = NewRenderer()
+		}
+		g.renderer.Begin()
+		defer g.renderer.End()
+	}
 	g.rwMutex.RLock()
 	defer g.rwMutex.RUnlock()
 	for _, s := range g.children {
@@ -76,81 +115,207 @@ func (g *Group) Draw() {
 	}
 }
 
-// RotateAround rotates the grouparound the given point, by the given
-// angle in degrees.
-func (b *Group) RotateAround(x, y, angle float32) {
-	// b.modelMatrix = mathgl.Translate3D(x, y, 0).Mul4(mathgl.HomogRotate3DZ(angle)).Mul4(mathgl.Translate3D(b.x, b.y, 0))
-	// b.angle = angle
+// BringToFront moves s after the other children of the group, so
+// that it's drawn over the ones it overlaps in the same layer. It
+// does nothing if s is not in the group.
+func (g *Group) BringToFront(s Shape) {
+	g.rwMutex.Lock()
+	defer g.rwMutex.Unlock()
+	if i := g.indexOf(s); i >= 0 {
+		copy(g.children[i:], g.children[i+1:])
+		g.children[len(g.children)-1] = s
+	}
 }
 
-// Rotate rotates the group aroung its center.
-func (g *Group) Rotate(angle float32) {
+// SendToBack moves s before the other children of the group, so that
+// it's drawn under the ones it overlaps in the same layer. It does
+// nothing if s is not in the group.
+func (g *Group) SendToBack(s Shape) {
 	g.rwMutex.Lock()
 	defer g.rwMutex.Unlock()
-	for _, s := range g.children {
-		s.RotateAround(g.x, g.y, angle)
+	if i := g.indexOf(s); i >= 0 {
+		copy(g.children[1:i+1], g.children[:i])
+		g.children[0] = s
 	}
 }
 
-// Scale scales each shape of the group.
-func (g *Group) Scale(sx, sy float32) {
-	for _, s := range g.children {
-		s.Scale(sx, sy)
+// indexOf returns the position of s in the children of the group or
+// -1 if s is not in the group.
+func (g *Group) indexOf(s Shape) int {
+	for i, c := range g.children {
+		if c == s {
+			return i
+		}
 	}
+	return -1
 }
 
-// Move moves each shape of the group by dx, dy.
-func (g *Group) Move(dx, dy float32) {
-	g.rwMutex.Lock()
-	defer g.rwMutex.Unlock()
+func (g *Group) Vertices() []float32 {
+	v := []float32{}
+
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+
 	for _, s := range g.children {
-		s.Move(dx, dy)
+		v = append(v, s.Vertices()...)
 	}
-	g.x, g.y = g.x+dx, g.y+dy
+
+	return v
 }
 
-// MoveTo moves the group in the (x,y) position.
-func (g *Group) MoveTo(x, y float32) {
-	g.rwMutex.Lock()
-	defer g.rwMutex.Unlock()
-	dx, dy := x-g.x, y-g.y
+// Contains returns true if (x, y), in world coordinates, lies inside
+// one of the children.
+func (g *Group) Contains(x, y float32) bool {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
 	for _, s := range g.children {
-		s.Move(dx, dy)
+		if s.Contains(x, y) {
+			return true
+		}
 	}
-	g.x, g.y = x, y
+	return false
 }
 
-func (g *Group) Vertices() []float32 {
-	v := []float32{}
+// PickAt returns the shapes containing (x, y), in world coordinates,
+// starting from the top-most one, that is by layer (see SetZ) from
+// the highest and, within the same layer, in the reverse drawing
+// order. Nested groups are searched recursively and only the shapes
+// they contain are returned.
+func (g *Group) PickAt(x, y float32) []Shape {
+	picked := g.appendPicked(nil, x, y)
+	sort.SliceStable(picked, func(i, j int) bool { return worldZOf(picked[i]) > worldZOf(picked[j]) })
+	return picked
+}
 
+// appendPicked appends to picked the shapes containing (x, y), in
+// the reverse drawing order.
+func (g *Group) appendPicked(picked []Shape, x, y float32) []Shape {
 	g.rwMutex.RLock()
 	defer g.rwMutex.RUnlock()
+	for i := len(g.children) - 1; i >= 0; i-- {
+		s := g.children[i]
+		if group, ok := s.(*Group); ok {
+			picked = group.appendPicked(picked, x, y)
+		} else if s.Contains(x, y) {
+			picked = append(picked, s)
+		}
+	}
+	return picked
+}
 
+// worldZOf returns the layer of s, including the ones of the groups
+// containing it.
+func worldZOf(s Shape) float32 {
+	if l, ok := s.(interface {
+		worldZ() float32
+	}); ok {
+		return l.worldZ()
+	}
+	return s.Z()
+}
+
+// hulls returns the convex pieces of all the children.
+func (g *Group) hulls() []hull {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	var hulls []hull
 	for _, s := range g.children {
-		v = append(v, s.Vertices()...)
+		hulls = append(hulls, hullsOf(s)...)
 	}
+	return hulls
+}
 
-	return v
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits one of the children
+// within maxDist. The shape reported by the hit is the child, or the
+// shape contained in a nested group, hit first. On ties the top-most
+// shape wins, that is the one in the highest layer (see SetZ) and,
+// within the same layer, the one drawn last.
+func (g *Group) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	var (
+		hit   RayHit
+		found bool
+	)
+	for i := len(g.children) - 1; i >= 0; i-- {
+		if h, ok := g.children[i].Raycast(originX, originY, dirX, dirY, maxDist); ok && (!found || h.Distance < hit.Distance ||
+			h.Distance == hit.Distance && worldZOf(h.Shape) > worldZOf(hit.Shape)) {
+			hit, found = h, true
+		}
+	}
+	return hit, found
+}
+
+// SetAnchor sets the pivot of the group in coordinates normalized to
+// the rectangle enclosing its children: (0, 0) is the bottom-left
+// corner and (1, 1) the top-right one.
+func (g *Group) SetAnchor(ax, ay float32) {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	b := g.childrenBounds()
+	g.SetPivot(b.MinX+ax*b.Dx(), b.MinY+ay*b.Dy())
 }
 
-// Center returns the center of the group.
-func (g *Group) Center() (float32, float32) {
-	return g.x, g.y
+// Bounds returns the rectangle enclosing the children, once
+// transformed by the group.
+func (g *Group) Bounds() Rect {
+	return rectOf(g.appendPoints(nil, mathgl.Ident4f()))
 }
 
-// SetCenter sets a new center for the group.
-func (g *Group) SetCenter(x, y float32) {
-	g.x, g.y = x, y
+// WorldVertices returns the vertices of the children in world
+// coordinates.
+func (g *Group) WorldVertices() []float32 {
+	return g.appendPoints(nil, g.parentMatrix())
 }
 
-// Angle returns the rotation angle of the group.
-func (g *Group) Angle() float32 {
-	return g.angle
+// AABB returns the axis-aligned rectangle enclosing the children in
+// world coordinates.
+func (g *Group) AABB() Rect {
+	return rectOf(g.WorldVertices())
 }
 
-// Bounds returns the bounding rectangle of the group.
-func (g *Group) Bounds() image.Rectangle {
-	return g.bounds
+// OBB returns the smallest box enclosing the children in world
+// coordinates, oriented as the group.
+func (g *Group) OBB() OBB {
+	return orientedBounds(g.WorldVertices(), g.worldMatrix())
+}
+
+// appendPoints appends to points the vertices of the children
+// transformed by the group and then by m.
+func (g *Group) appendPoints(points []float32, m mathgl.Mat4f) []float32 {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+
+	g.updateMatrix()
+	return g.appendChildrenPoints(points, m.Mul4(g.matrix))
+}
+
+// appendChildrenPoints appends to points the vertices of the
+// children transformed by m. Children not implementing child
+// contribute the corners of their bounds.
+func (g *Group) appendChildrenPoints(points []float32, m mathgl.Mat4f) []float32 {
+	for _, s := range g.children {
+		if c, ok := s.(child); ok {
+			points = c.appendPoints(points, m)
+			continue
+		}
+		b := s.Bounds()
+		points = appendTransformed(points, []float32{b.MinX, b.MinY, b.MaxX, b.MinY, b.MaxX, b.MaxY, b.MinX, b.MaxY}, m)
+	}
+	return points
+}
+
+// Release frees the GPU resources of the children.
+func (g *Group) Release() {
+	g.rwMutex.RLock()
+	defer g.rwMutex.RUnlock()
+	for _, s := range g.children {
+		s.Release()
+	}
+	if g.renderer != nil {
+		g.renderer.Release()
+	}
 }
 
 // String returns a textual representation of the group.
@@ -175,19 +340,21 @@ func (g *Group) AttachToWorld(world World) {
 	}
 }
 
-// Clone returns a copy of the group.
+// Clone returns a copy of the group, placed as the group, with a
+// copy of its children.
 func (g *Group) Clone() Shape {
 	g.rwMutex.RLock()
 	defer g.rwMutex.RUnlock()
 
 	cg := NewGroup()
+	cg.copyTransform(&g.transform)
 
 	for _, s := range g.children {
 		cs := s.Clone()
 		cg.Append(cs)
 	}
 
-	return g
+	return cg
 }
 
 // SetTexture sets the same texture to all shapes in the group.
@@ -0,0 +1,149 @@
+package shapes
+
+import (
+	"fmt"
+	"image/color"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Mesh represents a generic shape made of triangles.
+type Mesh struct {
+	Base
+
+	// Indices of the vertices of each triangle. If nil, each
+	// three consecutive vertices form a triangle.
+	indices []uint16
+
+	// Texture coordinates given at creation time
+	uvs []float32
+}
+
+// NewMesh creates a new mesh of triangles. It takes as arguments a
+// linked program (e.g. built from DefaultBoxVS and DefaultBoxFS) and
+// the (x, y) positions of the vertices. The other arguments are
+// optional and can be nil:
+//
+//   - indices lists the vertices of each triangle; if nil each three
+//     consecutive positions form a triangle;
+//   - colors sets a color for each vertex (see SetVertexColors); if
+//     nil the mesh has the default color;
+//   - texCoords sets the texture coordinates of each vertex; they are
+//     used when SetTexture is called with nil coordinates.
+//
+// The center of the mesh is placed at the center of the rectangle
+// bounding the positions. An error is returned if the arguments are
+// inconsistent.
+func NewMesh(program shaders.Program, positions []float32, indices []uint16, colors []color.Color, texCoords []float32) (*Mesh, error) {
+
+	vCount := len(positions) / 2
+	if len(positions)%2 != 0 {
+		return nil, fmt.Errorf("positions must contain (x, y) pairs")
+	}
+	for _, i := range indices {
+		if int(i) >= vCount {
+			return nil, fmt.Errorf("index %d out of range, the mesh has %d vertices", i, vCount)
+		}
+	}
+	if texCoords != nil && len(texCoords) != len(positions) {
+		return nil, fmt.Errorf("the mesh has %d vertices but %d texture coordinates were given", vCount, len(texCoords)/2)
+	}
+
+	mesh := new(Mesh)
+
+	minX, minY, maxX, maxY := boundsOf(positions)
+
+	// Center of the mesh
+	mesh.x = (minX + maxX) / 2
+	mesh.y = (minY + maxY) / 2
+
+	// The vertices are stored relative to the center
+	mesh.vertices = make([]float32, len(positions))
+	for i := 0; i < len(positions); i += 2 {
+		mesh.vertices[i] = positions[i] - mesh.x
+		mesh.vertices[i+1] = positions[i+1] - mesh.y
+	}
+	mesh.indices = indices
+	mesh.uvs = texCoords
+
+	// Set the default color
+	mesh.SetColor(DefaultColor)
+	if colors != nil {
+		if err := mesh.SetVertexColors(colors); err != nil {
+			return nil, err
+		}
+	}
+
+	mesh.initTexturedProgram(program)
+
+	// Place the mesh where its positions were given.
+	mesh.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	mesh.updateBounds()
+
+	return mesh, nil
+}
+
+// Indices returns the indices of the vertices of each triangle.
+func (mesh *Mesh) Indices() []uint16 {
+	return mesh.indices
+}
+
+// SetTexture sets a texture for the mesh. If texCoords is nil the
+// texture coordinates given at creation time are used or, if none
+// were given, they are generated so that the whole texture is mapped
+// on the rectangle bounding the mesh.
+func (mesh *Mesh) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = mesh.uvs
+	}
+	if texCoords == nil {
+		texCoords = mesh.boundsTexCoords()
+	}
+	return mesh.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies in one
+// of the triangles of the mesh.
+func (mesh *Mesh) Contains(x, y float32) bool {
+	return mesh.containsLocal(gl.TRIANGLES, mesh.indices, x, y)
+}
+
+// hulls returns the triangles of the mesh.
+func (mesh *Mesh) hulls() []hull {
+	return mesh.triangleHulls(gl.TRIANGLES, mesh.indices)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the mesh within
+// maxDist.
+func (mesh *Mesh) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(mesh, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the mesh on the surface.
+func (mesh *Mesh) Draw() {
+	if len(mesh.vertices) == 0 {
+		return
+	}
+	if mesh.indices != nil {
+		if len(mesh.indices) > 0 {
+			mesh.drawElements(gl.TRIANGLES, mesh.indices)
+		}
+		return
+	}
+	mesh.drawArrays(gl.TRIANGLES)
+}
+
+// Clone makes a copy of the mesh. The copy is centered at the
+// origin.
+func (mesh *Mesh) Clone() Shape {
+	m, _ := NewMesh(mesh.program, mesh.vertices, mesh.indices, nil, mesh.uvs)
+	m.copyColors(&mesh.Base)
+	if len(mesh.texCoords) > 0 {
+		m.SetTexture(mesh.texBuffer, mesh.texCoords)
+	}
+	return m
+}
@@ -0,0 +1,244 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Insets are the sizes in pixels of the borders of a nine-patch
+// texture that are not stretched.
+type Insets struct {
+	Left, Top, Right, Bottom float32
+}
+
+// ninePatchIndices are the indices of the triangles of the nine
+// regions, given a 4x4 grid of vertices listed row by row from the
+// bottom-left one.
+var ninePatchIndices = func() []uint16 {
+	indices := make([]uint16, 0, 9*6)
+	for row := uint16(0); row < 3; row++ {
+		for col := uint16(0); col < 3; col++ {
+			i := row*4 + col
+			indices = append(indices, i, i+1, i+4, i+4, i+1, i+5)
+		}
+	}
+	return indices
+}()
+
+// NinePatch is a textured box split in nine regions, useful for
+// scalable UI panels. When the box is resized the corners keep their
+// size, the edges are stretched along one axis and the center along
+// both.
+type NinePatch struct {
+	Base
+
+	// Size of the box
+	width, height float32
+
+	// Size of the texture in pixels
+	texWidth, texHeight float32
+
+	// Borders of the texture that are not stretched
+	insets Insets
+}
+
+// NewNinePatch creates a new nine-patch box as big as the texture.
+// It takes as arguments a linked program (e.g. built from
+// DefaultBoxVS and DefaultBoxFS), the texture, its size in pixels
+// and the borders that are not stretched. Use SetSize to resize the
+// box: unlike Scale, it doesn't stretch the corners.
+func NewNinePatch(program shaders.Program, texture uint32, texWidth, texHeight int, insets Insets) *NinePatch {
+
+	patch := new(NinePatch)
+
+	patch.texWidth, patch.texHeight = float32(texWidth), float32(texHeight)
+	patch.insets = insets
+
+	// The box is built around its center at (0, 0) as a 4x4 grid
+	// of vertices.
+	patch.vertices = make([]float32, 16*2)
+	patch.setSize(patch.texWidth, patch.texHeight)
+
+	// Map the texture on the grid
+	patch.texBuffer = texture
+	patch.texCoords = patch.insetTexCoords()
+
+	// Set the default color
+	patch.SetColor(DefaultColor)
+
+	patch.initTexturedProgram(program)
+
+	// Start with no rotation and unit scale.
+	patch.initTransform()
+
+	return patch
+}
+
+// Size returns the size of the box.
+func (patch *NinePatch) Size() (float32, float32) {
+	return patch.width, patch.height
+}
+
+// SetSize resizes the box. If the box is smaller than the sum of two
+// opposite insets, the corresponding borders are shrunk
+// proportionally.
+func (patch *NinePatch) SetSize(width, height float32) {
+	patch.setSize(width, height)
+}
+
+// setSize rebuilds the grid of vertices for the given size.
+func (patch *NinePatch) setSize(width, height float32) {
+	patch.width, patch.height = width, height
+
+	left, right := fitInsets(patch.insets.Left, patch.insets.Right, width)
+	bottom, top := fitInsets(patch.insets.Bottom, patch.insets.Top, height)
+
+	xs := [4]float32{-width / 2, -width/2 + left, width/2 - right, width / 2}
+	ys := [4]float32{-height / 2, -height/2 + bottom, height/2 - top, height / 2}
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			i := (row*4 + col) * 2
+			patch.vertices[i], patch.vertices[i+1] = xs[col], ys[row]
+		}
+	}
+
+	patch.updateBounds()
+}
+
+// fitInsets shrinks two opposite insets proportionally if they don't
+// fit in the given size.
+func fitInsets(a, b, size float32) (float32, float32) {
+	if a+b > size && a+b > 0 {
+		k := size / (a + b)
+		return a * k, b * k
+	}
+	return a, b
+}
+
+// Insets returns the borders of the texture that are not stretched.
+func (patch *NinePatch) Insets() Insets {
+	return patch.insets
+}
+
+// SetTexture sets a texture for the box. If texCoords is nil the
+// texture is mapped on the nine regions using the insets, otherwise
+// texCoords must contain the coordinates of the 4x4 vertices of the
+// grid, listed row by row from the bottom-left one.
+func (patch *NinePatch) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		return patch.Base.SetTexture(texture, patch.insetTexCoords())
+	}
+	if len(texCoords) != len(patch.vertices) {
+		return fmt.Errorf("expected %d texture coordinates, got %d", len(patch.vertices)/2, len(texCoords)/2)
+	}
+	return patch.Base.SetTexture(texture, texCoords)
+}
+
+// insetTexCoords returns the texture coordinates mapping the nine
+// regions of the texture, delimited by the insets, on the grid.
+func (patch *NinePatch) insetTexCoords() []float32 {
+	us := [4]float32{0, patch.insets.Left / patch.texWidth, 1 - patch.insets.Right/patch.texWidth, 1}
+	vs := [4]float32{0, patch.insets.Bottom / patch.texHeight, 1 - patch.insets.Top/patch.texHeight, 1}
+	texCoords := make([]float32, 0, 16*2)
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			texCoords = append(texCoords, us[col], vs[row])
+		}
+	}
+	return texCoords
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the box.
+func (patch *NinePatch) Contains(x, y float32) bool {
+	lx, ly, ok := patch.toLocal(x, y)
+	return ok && patch.localBounds.Contains(lx, ly)
+}
+
+// hulls returns the box as a single convex piece.
+func (patch *NinePatch) hulls() []hull {
+	return patch.boundsHull()
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the box within
+// maxDist.
+func (patch *NinePatch) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(patch, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the box on the surface.
+func (patch *NinePatch) Draw() {
+	patch.drawElements(gl.TRIANGLES, ninePatchIndices)
+}
+
+// Clone makes a copy of the box.
+func (patch *NinePatch) Clone() Shape {
+	p := NewNinePatch(patch.program, patch.texBuffer, int(patch.texWidth), int(patch.texHeight), patch.insets)
+	p.SetSize(patch.width, patch.height)
+	p.copyColors(&patch.Base)
+	p.texCoords = append(p.texCoords[:0], patch.texCoords...)
+	return p
+}
+
+// ParseNinePatch decodes the markers of an Android nine-patch image
+// (.9.png). The one pixel wide border of the image marks with black
+// pixels the stretchable regions: the top row horizontally and the
+// left column vertically. Only one stretchable region per axis is
+// supported, multiple marked regions are merged. The content
+// padding markers in the bottom row and in the right column are
+// ignored. It returns the image without the border, ready to be
+// uploaded as a texture, and the insets to pass to NewNinePatch.
+func ParseNinePatch(img image.Image) (image.Image, Insets, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 3 || h < 3 {
+		return nil, Insets{}, fmt.Errorf("image is too small to be a nine-patch")
+	}
+
+	isMarker := func(x, y int) bool {
+		c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+		return c.A == 0xff && c.R == 0 && c.G == 0 && c.B == 0
+	}
+
+	x0, x1 := -1, -1
+	for x := 1; x < w-1; x++ {
+		if isMarker(x, 0) {
+			if x0 < 0 {
+				x0 = x
+			}
+			x1 = x
+		}
+	}
+	y0, y1 := -1, -1
+	for y := 1; y < h-1; y++ {
+		if isMarker(0, y) {
+			if y0 < 0 {
+				y0 = y
+			}
+			y1 = y
+		}
+	}
+	if x0 < 0 || y0 < 0 {
+		return nil, Insets{}, fmt.Errorf("cannot find the nine-patch stretch markers")
+	}
+
+	// Coordinates of the content are shifted by the border
+	cw, ch := w-2, h-2
+	insets := Insets{
+		Left:   float32(x0 - 1),
+		Top:    float32(y0 - 1),
+		Right:  float32(cw - x1),
+		Bottom: float32(ch - y1),
+	}
+
+	content := image.NewNRGBA(image.Rect(0, 0, cw, ch))
+	draw.Draw(content, content.Bounds(), img, b.Min.Add(image.Point{1, 1}), draw.Src)
+
+	return content, insets, nil
+}
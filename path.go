@@ -0,0 +1,292 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Path describes a vector outline made of one or more subpaths,
+// in the style of SVG paths. Curves are flattened as they are added
+// to the path. A path is just a description: call Fill or Stroke to
+// obtain a shape that can be rendered.
+type Path struct {
+	// Flattened points of each subpath
+	subpaths [][]float32
+
+	// Whether each subpath is closed
+	closed []bool
+
+	// Current point
+	x, y float32
+
+	// Whether the current subpath accepts new segments
+	open bool
+
+	// Maximum distance between curves and their flattened
+	// approximation
+	tolerance float32
+}
+
+// NewPath returns an empty path.
+func NewPath() *Path {
+	return &Path{tolerance: curveTolerance(DefaultCurveTolerance)}
+}
+
+// SetTolerance sets the maximum distance in pixels between the
+// curves added from now on and their flattened approximation. A
+// tolerance that is not positive is replaced by
+// DefaultCurveTolerance.
+func (p *Path) SetTolerance(tolerance float32) {
+	p.tolerance = curveTolerance(tolerance)
+}
+
+// MoveTo starts a new subpath at (x, y).
+func (p *Path) MoveTo(x, y float32) {
+	p.subpaths = append(p.subpaths, []float32{x, y})
+	p.closed = append(p.closed, false)
+	p.x, p.y = x, y
+	p.open = true
+}
+
+// current returns the subpath segments are appended to. If there is
+// no open subpath a new one is started at the current point.
+func (p *Path) current() *[]float32 {
+	if !p.open {
+		p.MoveTo(p.x, p.y)
+	}
+	return &p.subpaths[len(p.subpaths)-1]
+}
+
+// LineTo adds a straight line from the current point to (x, y).
+func (p *Path) LineTo(x, y float32) {
+	sp := p.current()
+	*sp = append(*sp, x, y)
+	p.x, p.y = x, y
+}
+
+// QuadTo adds a quadratic Bezier curve from the current point to
+// (x, y) using (x1, y1) as control point.
+func (p *Path) QuadTo(x1, y1, x, y float32) {
+	sp := p.current()
+	*sp = flattenQuad(*sp, p.x, p.y, x1, y1, x, y, p.tolerance, 0)
+	p.x, p.y = x, y
+}
+
+// CubicTo adds a cubic Bezier curve from the current point to
+// (x, y) using (x1, y1) and (x2, y2) as control points.
+func (p *Path) CubicTo(x1, y1, x2, y2, x, y float32) {
+	sp := p.current()
+	*sp = flattenCubic(*sp, p.x, p.y, x1, y1, x2, y2, x, y, p.tolerance, 0)
+	p.x, p.y = x, y
+}
+
+// ArcTo adds an elliptical arc from the current point to (x, y),
+// like the SVG arc command. rx and ry are the radii of the ellipse
+// and rotation is the angle in degrees of its x axis. Among the four
+// candidate arcs, largeArc selects the one spanning more than 180
+// degrees and sweep the one drawn in the direction of increasing
+// angles (counterclockwise). Radii too small to reach (x, y) are
+// scaled up.
+func (p *Path) ArcTo(rx, ry, rotation float32, largeArc, sweep bool, x, y float32) {
+	sp := p.current()
+	*sp = flattenArc(*sp, p.x, p.y, rx, ry, rotation, largeArc, sweep, x, y, p.tolerance)
+	p.x, p.y = x, y
+}
+
+// Close closes the current subpath. The current point moves back to
+// the start of the subpath and the next segment starts a new one.
+func (p *Path) Close() {
+	if !p.open {
+		return
+	}
+	i := len(p.subpaths) - 1
+	p.closed[i] = true
+	p.x, p.y = p.subpaths[i][0], p.subpaths[i][1]
+	p.open = false
+}
+
+// Fill returns a shape filling the path according to the given rule.
+// All subpaths are implicitly closed, so that inner subpaths can
+// describe holes. It takes as argument a linked program (e.g. built
+// from DefaultBoxVS and DefaultBoxFS).
+func (p *Path) Fill(program shaders.Program, rule FillRule) *PathShape {
+	return newPathShape(program, fill(p.subpaths, rule), gl.TRIANGLES, true)
+}
+
+// Stroke returns a shape stroking the path with the given width and
+// style. It takes as argument a linked program (e.g. built from
+// DefaultSegmentVS and DefaultSegmentFS).
+func (p *Path) Stroke(program shaders.Program, width float32, join Join, endCap Cap) *PathShape {
+	var strip []float32
+	for i, sp := range p.subpaths {
+		if p.closed[i] {
+			strip = appendStrip(strip, strokeClosed(sp, width, join, DefaultMiterLimit))
+		} else {
+			strip = appendStrip(strip, stroke(sp, width, join, endCap, DefaultMiterLimit))
+		}
+	}
+	return newPathShape(program, strip, gl.TRIANGLE_STRIP, false)
+}
+
+// PathShape is a shape obtained filling or stroking a Path.
+type PathShape struct {
+	Base
+
+	// Primitive used to render the vertices
+	mode gl.Enum
+}
+
+// newPathShape creates a new shape from vertices rendered with the
+// given primitive. The shape is centered at the center of the
+// rectangle bounding the vertices.
+func newPathShape(program shaders.Program, vertices []float32, mode gl.Enum, textured bool) *PathShape {
+
+	shape := new(PathShape)
+	shape.mode = mode
+
+	minX, minY, maxX, maxY := boundsOf(vertices)
+
+	// Center of the shape
+	shape.x = (minX + maxX) / 2
+	shape.y = (minY + maxY) / 2
+
+	// The vertices are stored relative to the center
+	shape.vertices = make([]float32, len(vertices))
+	for i := 0; i < len(vertices); i += 2 {
+		shape.vertices[i] = vertices[i] - shape.x
+		shape.vertices[i+1] = vertices[i+1] - shape.y
+	}
+
+	// Set the default color
+	shape.SetColor(DefaultColor)
+
+	if textured {
+		shape.initTexturedProgram(program)
+	} else {
+		shape.initProgram(program)
+	}
+
+	// Place the shape where the path was described.
+	shape.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	shape.updateBounds()
+
+	return shape
+}
+
+// SetTexture sets a texture for a filled path. If texCoords is nil
+// the texture coordinates are generated so that the whole texture is
+// mapped on the rectangle bounding the shape.
+func (shape *PathShape) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = shape.boundsTexCoords()
+	}
+	return shape.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies in the
+// filled region or on the stroke of the shape.
+func (shape *PathShape) Contains(x, y float32) bool {
+	return shape.containsLocal(shape.mode, nil, x, y)
+}
+
+// hulls returns the triangles of the shape.
+func (shape *PathShape) hulls() []hull {
+	return shape.triangleHulls(shape.mode, nil)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the shape within
+// maxDist.
+func (shape *PathShape) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(shape, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the shape on the surface.
+func (shape *PathShape) Draw() {
+	if len(shape.vertices) == 0 {
+		return
+	}
+	shape.drawArrays(shape.mode)
+}
+
+// Clone makes a copy of the shape. The copy is centered at the
+// origin.
+func (shape *PathShape) Clone() Shape {
+	s := newPathShape(shape.program, shape.vertices, shape.mode, shape.textured)
+	s.copyColors(&shape.Base)
+	if len(shape.texCoords) > 0 {
+		s.SetTexture(shape.texBuffer, shape.texCoords)
+	}
+	return s
+}
+
+// flattenArc appends to points the end points of the segments
+// approximating an SVG-like elliptical arc from (x0, y0) to (x, y)
+// within the given tolerance. The start point is not appended.
+func flattenArc(points []float32, x0, y0, rx, ry, rotation float32, largeArc, sweep bool, x, y, tolerance float32) []float32 {
+	if x0 == x && y0 == y {
+		return points
+	}
+	if rx == 0 || ry == 0 {
+		return append(points, x, y)
+	}
+
+	// Convert the endpoint parameterization to the center one
+	// (see the SVG specification, appendix F.6.5).
+	phi := radians(rotation)
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	frx, fry := math.Abs(float64(rx)), math.Abs(float64(ry))
+	dx2, dy2 := float64(x0-x)/2, float64(y0-y)/2
+	x1p := cosPhi*dx2 + sinPhi*dy2
+	y1p := -sinPhi*dx2 + cosPhi*dy2
+
+	// Scale up radii too small to reach the end point
+	if l := x1p*x1p/(frx*frx) + y1p*y1p/(fry*fry); l > 1 {
+		frx, fry = frx*math.Sqrt(l), fry*math.Sqrt(l)
+	}
+
+	num := frx*frx*fry*fry - frx*frx*y1p*y1p - fry*fry*x1p*x1p
+	den := frx*frx*y1p*y1p + fry*fry*x1p*x1p
+	coef := math.Sqrt(math.Max(0, num/den))
+	if largeArc == sweep {
+		coef = -coef
+	}
+	cxp, cyp := coef*frx*y1p/fry, -coef*fry*x1p/frx
+	cx := cosPhi*cxp - sinPhi*cyp + float64(x0+x)/2
+	cy := sinPhi*cxp + cosPhi*cyp + float64(y0+y)/2
+
+	theta := math.Atan2((y1p-cyp)/fry, (x1p-cxp)/frx)
+	delta := math.Atan2((-y1p-cyp)/fry, (-x1p-cxp)/frx) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// The angle spanned by a segment whose distance from the
+	// arc is within the tolerance
+	r := math.Max(frx, fry)
+	step := math.Pi / 2
+	if t := float64(tolerance); t > 0 && t < r {
+		step = math.Min(step, 2*math.Acos(1-t/r))
+	}
+	n := int(math.Ceil(math.Abs(delta) / step))
+	if n < 1 {
+		n = 1
+	}
+
+	for i := 1; i < n; i++ {
+		a := theta + delta*float64(i)/float64(n)
+		ex, ey := frx*math.Cos(a), fry*math.Sin(a)
+		points = append(
+			points,
+			float32(cx+cosPhi*ex-sinPhi*ey),
+			float32(cy+sinPhi*ex+cosPhi*ey),
+		)
+	}
+	return append(points, x, y)
+}
@@ -0,0 +1,98 @@
+package shapes
+
+import (
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Pie represents a filled circular sector (a pie slice).
+type Pie struct {
+	Base
+
+	// Radius of the pie
+	radius float32
+
+	// Start and end angles in degrees
+	startAngle, endAngle float32
+
+	// Number of segments approximating the arc
+	segments int
+}
+
+// NewPie creates a new pie slice centered at (0, 0). It takes as
+// arguments a linked program, the radius, the start and end angles
+// in degrees (counterclockwise from the positive x axis) and the
+// number of segments used to approximate the arc. The number of
+// segments doesn't depend on the angles so that they can be changed
+// without reallocating the vertices.
+func NewPie(program shaders.Program, radius, startAngle, endAngle float32, segments int) *Pie {
+
+	pie := new(Pie)
+
+	if segments < 1 {
+		segments = 1
+	}
+
+	pie.radius = radius
+	pie.segments = segments
+	pie.vertices = make([]float32, 0, (segments+2)*2)
+	pie.SetAngles(startAngle, endAngle)
+
+	// Set the default color
+	pie.SetColor(DefaultColor)
+
+	pie.initProgram(program)
+
+	// Start with no rotation and unit scale.
+	pie.initTransform()
+
+	return pie
+}
+
+// Angles returns the start and end angles of the pie in degrees.
+func (pie *Pie) Angles() (float32, float32) {
+	return pie.startAngle, pie.endAngle
+}
+
+// SetAngles sets the start and end angles of the pie in degrees. The
+// vertices are rebuilt in place.
+func (pie *Pie) SetAngles(startAngle, endAngle float32) {
+	pie.startAngle, pie.endAngle = startAngle, endAngle
+	pie.vertices = ellipseFan(
+		pie.vertices[:0],
+		pie.radius, pie.radius,
+		radians(startAngle), radians(endAngle-startAngle),
+		pie.segments,
+	)
+	pie.updateBounds()
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the pie.
+func (pie *Pie) Contains(x, y float32) bool {
+	return pie.containsLocal(gl.TRIANGLE_FAN, nil, x, y)
+}
+
+// hulls returns the triangles of the pie.
+func (pie *Pie) hulls() []hull {
+	return pie.triangleHulls(gl.TRIANGLE_FAN, nil)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the pie within
+// maxDist.
+func (pie *Pie) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(pie, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the pie on the surface.
+func (pie *Pie) Draw() {
+	pie.drawArrays(gl.TRIANGLE_FAN)
+}
+
+// Clone makes a copy of the pie.
+func (pie *Pie) Clone() Shape {
+	p := NewPie(pie.program, pie.radius, pie.startAngle, pie.endAngle, pie.segments)
+	p.copyColors(&pie.Base)
+	return p
+}
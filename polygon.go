@@ -0,0 +1,244 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Polygon represents a filled polygon. The outline of the polygon
+// can be either convex or concave but it must not intersect itself.
+type Polygon struct {
+	Base
+
+	// Outline of the polygon relative to its center
+	outline []float32
+}
+
+// NewPolygon creates a new polygon. It takes as arguments a linked
+// program and the (x, y) coordinates of the outline vertices, in
+// clockwise or counterclockwise order. A trailing coordinate without
+// its pair is ignored. The center of the polygon is placed at the
+// center of the rectangle bounding the outline.
+func NewPolygon(program shaders.Program, vertices []float32) *Polygon {
+
+	vertices = vertices[:len(vertices)/2*2]
+	minX, minY, maxX, maxY := boundsOf(vertices)
+
+	// Center of the polygon
+	x, y := (minX+maxX)/2, (minY+maxY)/2
+
+	// The outline is stored relative to the center of the polygon
+	outline := make([]float32, len(vertices))
+	for i := 0; i < len(vertices); i += 2 {
+		outline[i] = vertices[i] - x
+		outline[i+1] = vertices[i+1] - y
+	}
+
+	return newPolygon(program, outline, x, y)
+}
+
+// newPolygon creates a new polygon centered in (x, y) from an
+// outline relative to the center.
+func newPolygon(program shaders.Program, outline []float32, x, y float32) *Polygon {
+
+	polygon := new(Polygon)
+
+	polygon.x, polygon.y = x, y
+	polygon.outline = outline
+
+	// Triangulate the outline
+	indices := triangulate(polygon.outline)
+	polygon.vertices = make([]float32, 0, len(indices)*2)
+	for _, i := range indices {
+		polygon.vertices = append(polygon.vertices, polygon.outline[i*2], polygon.outline[i*2+1])
+	}
+
+	// Set the default color
+	polygon.SetColor(DefaultColor)
+
+	polygon.initTexturedProgram(program)
+
+	// Place the polygon where its outline was given.
+	polygon.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	polygon.updateBounds()
+
+	return polygon
+}
+
+// Outline returns the vertices of the outline of the polygon,
+// relative to its center. Vertices returns instead the vertices of
+// the triangles actually rendered.
+func (polygon *Polygon) Outline() []float32 {
+	return polygon.outline
+}
+
+// SetTexture sets a texture for the polygon. If texCoords is nil the
+// texture coordinates are generated so that the whole texture is
+// mapped on the rectangle bounding the polygon.
+func (polygon *Polygon) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = polygon.boundsTexCoords()
+	}
+	return polygon.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the polygon, that is if the outline winds around it.
+func (polygon *Polygon) Contains(x, y float32) bool {
+	lx, ly, ok := polygon.toLocal(x, y)
+	return ok && winding(polygon.outline, lx, ly) != 0
+}
+
+// hulls returns the outline of a convex polygon as a single piece,
+// otherwise its triangles.
+func (polygon *Polygon) hulls() []hull {
+	if isConvex(polygon.outline) {
+		return polygon.outlineHull(polygon.outline)
+	}
+	return polygon.triangleHulls(gl.TRIANGLES, nil)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the polygon within
+// maxDist.
+func (polygon *Polygon) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(polygon, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the polygon on the surface.
+func (polygon *Polygon) Draw() {
+	if len(polygon.vertices) == 0 {
+		return
+	}
+	polygon.drawArrays(gl.TRIANGLES)
+}
+
+// Clone makes a copy of the polygon. The copy is centered at the
+// origin.
+func (polygon *Polygon) Clone() Shape {
+	p := newPolygon(polygon.program, polygon.outline, 0, 0)
+	p.copyColors(&polygon.Base)
+	if len(polygon.texCoords) > 0 {
+		p.SetTexture(polygon.texBuffer, polygon.texCoords)
+	}
+	return p
+}
+
+// boundsOf returns the minimum and maximum coordinates of the given
+// (x, y) points.
+func boundsOf(points []float32) (minX, minY, maxX, maxY float32) {
+	if len(points) < 2 {
+		return
+	}
+	minX, minY = points[0], points[1]
+	maxX, maxY = minX, minY
+	for i := 2; i+1 < len(points); i += 2 {
+		minX = float32(math.Min(float64(minX), float64(points[i])))
+		maxX = float32(math.Max(float64(maxX), float64(points[i])))
+		minY = float32(math.Min(float64(minY), float64(points[i+1])))
+		maxY = float32(math.Max(float64(maxY), float64(points[i+1])))
+	}
+	return
+}
+
+// signedArea returns the signed area of the polygon described by the
+// given (x, y) points. The area is positive if the points are in
+// counterclockwise order.
+func signedArea(points []float32) float32 {
+	var area float32
+	n := len(points) / 2
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += points[i*2]*points[j*2+1] - points[j*2]*points[i*2+1]
+	}
+	return area / 2
+}
+
+// cross returns the z component of the cross product between the
+// vectors (a, b) and (a, c).
+func cross(ax, ay, bx, by, cx, cy float32) float32 {
+	return (bx-ax)*(cy-ay) - (by-ay)*(cx-ax)
+}
+
+// inTriangle returns true if (px, py) lies inside or on the border
+// of the counterclockwise triangle (a, b, c).
+func inTriangle(px, py, ax, ay, bx, by, cx, cy float32) bool {
+	return cross(ax, ay, bx, by, px, py) >= 0 &&
+		cross(bx, by, cx, cy, px, py) >= 0 &&
+		cross(cx, cy, ax, ay, px, py) >= 0
+}
+
+// triangulate splits the polygon described by the given (x, y)
+// points into triangles using the ear clipping method. It returns
+// the indices of the points of each counterclockwise triangle.
+func triangulate(points []float32) []int {
+	n := len(points) / 2
+	if n < 3 {
+		return nil
+	}
+
+	// Work on a counterclockwise list of indices
+	idx := make([]int, n)
+	ccw := signedArea(points) > 0
+	for i := range idx {
+		if ccw {
+			idx[i] = i
+		} else {
+			idx[i] = n - 1 - i
+		}
+	}
+
+	pt := func(i int) (float32, float32) {
+		return points[i*2], points[i*2+1]
+	}
+
+	isEar := func(prev, cur, next int) bool {
+		ax, ay := pt(prev)
+		bx, by := pt(cur)
+		cx, cy := pt(next)
+		// Reflex or degenerate vertices are not ears
+		if cross(ax, ay, bx, by, cx, cy) <= 0 {
+			return false
+		}
+		for _, i := range idx {
+			if i == prev || i == cur || i == next {
+				continue
+			}
+			px, py := pt(i)
+			if (px == ax && py == ay) || (px == bx && py == by) || (px == cx && py == cy) {
+				continue
+			}
+			if inTriangle(px, py, ax, ay, bx, by, cx, cy) {
+				return false
+			}
+		}
+		return true
+	}
+
+	triangles := make([]int, 0, (n-2)*3)
+	for len(idx) > 3 {
+		m := len(idx)
+		clipped := false
+		for i := 0; i < m; i++ {
+			prev, cur, next := idx[(i+m-1)%m], idx[i], idx[(i+1)%m]
+			if isEar(prev, cur, next) {
+				triangles = append(triangles, prev, cur, next)
+				idx = append(idx[:i], idx[i+1:]...)
+				clipped = true
+				break
+			}
+		}
+		// No ear was found, the outline is degenerate (collinear
+		// or self-intersecting points). Clip a vertex anyway in
+		// order to terminate.
+		if !clipped {
+			triangles = append(triangles, idx[m-1], idx[0], idx[1])
+			idx = idx[1:]
+		}
+	}
+	return append(triangles, idx[0], idx[1], idx[2])
+}
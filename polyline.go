@@ -0,0 +1,149 @@
+package shapes
+
+import (
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Polyline represents an open path stroked with a given width. The
+// path is tessellated in a triangle strip so the width doesn't
+// depend on the line width supported by the driver.
+type Polyline struct {
+	Base
+
+	// Points of the path relative to its center
+	points []float32
+
+	// Stroke style
+	width      float32
+	join       Join
+	cap        Cap
+	miterLimit float32
+}
+
+// NewPolyline creates a new polyline. It takes as arguments a linked
+// program (e.g. built from DefaultSegmentVS and DefaultSegmentFS),
+// the (x, y) coordinates of the points of the path and the stroke
+// width. By default segments are joined with MiterJoin and the ends
+// are terminated with ButtCap.
+func NewPolyline(program shaders.Program, points []float32, width float32) *Polyline {
+
+	polyline := new(Polyline)
+
+	minX, minY, maxX, maxY := boundsOf(points)
+
+	// Center of the polyline
+	polyline.x = (minX + maxX) / 2
+	polyline.y = (minY + maxY) / 2
+
+	// The points are stored relative to the center of the polyline
+	polyline.points = make([]float32, len(points))
+	for i := 0; i < len(points); i += 2 {
+		polyline.points[i] = points[i] - polyline.x
+		polyline.points[i+1] = points[i+1] - polyline.y
+	}
+
+	polyline.width = width
+	polyline.join = MiterJoin
+	polyline.cap = ButtCap
+	polyline.miterLimit = DefaultMiterLimit
+
+	polyline.color = DefaultColor
+	polyline.tessellate()
+
+	polyline.initProgram(program)
+
+	// Place the polyline where its points were given.
+	polyline.initTransform()
+
+	return polyline
+}
+
+// tessellate builds the triangle strip of the polyline and updates
+// its colors and bounds accordingly.
+func (polyline *Polyline) tessellate() {
+	polyline.vertices = stroke(polyline.points, polyline.width, polyline.join, polyline.cap, polyline.miterLimit)
+	polyline.updateColors()
+	polyline.updateBounds()
+}
+
+// Points returns the points of the path relative to the center of
+// the polyline.
+func (polyline *Polyline) Points() []float32 {
+	return polyline.points
+}
+
+// Width returns the stroke width.
+func (polyline *Polyline) Width() float32 {
+	return polyline.width
+}
+
+// SetWidth sets the stroke width.
+func (polyline *Polyline) SetWidth(width float32) {
+	polyline.width = width
+	polyline.tessellate()
+}
+
+// SetJoin sets the style used to join consecutive segments.
+func (polyline *Polyline) SetJoin(join Join) {
+	polyline.join = join
+	polyline.tessellate()
+}
+
+// SetCap sets the style used to terminate the ends of the path.
+func (polyline *Polyline) SetCap(c Cap) {
+	polyline.cap = c
+	polyline.tessellate()
+}
+
+// SetMiterLimit sets the maximum ratio between the miter length and
+// the stroke width. Beyond the limit miter joins are beveled.
+func (polyline *Polyline) SetMiterLimit(limit float32) {
+	polyline.miterLimit = limit
+	polyline.tessellate()
+}
+
+// Contains returns true if (x, y), in world coordinates, lies on the
+// stroke of the polyline or within DefaultHitTolerance from it.
+func (polyline *Polyline) Contains(x, y float32) bool {
+	if polyline.containsLocal(gl.TRIANGLE_STRIP, nil, x, y) {
+		return true
+	}
+	return polyline.nearLine(polyline.points, x, y)
+}
+
+// hulls returns the triangles of the stroke of the polyline or its
+// segments if it has no width.
+func (polyline *Polyline) hulls() []hull {
+	if len(polyline.vertices) > 0 {
+		return polyline.triangleHulls(gl.TRIANGLE_STRIP, nil)
+	}
+	return polyline.lineHulls(polyline.points)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the polyline within
+// maxDist.
+func (polyline *Polyline) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(polyline, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the polyline on the surface.
+func (polyline *Polyline) Draw() {
+	if len(polyline.vertices) == 0 {
+		return
+	}
+	polyline.drawArrays(gl.TRIANGLE_STRIP)
+}
+
+// Clone makes a copy of the polyline. The copy is centered at the
+// origin.
+func (polyline *Polyline) Clone() Shape {
+	p := NewPolyline(polyline.program, polyline.points, polyline.width)
+	p.join = polyline.join
+	p.cap = polyline.cap
+	p.miterLimit = polyline.miterLimit
+	p.tessellate()
+	p.copyColors(&polyline.Base)
+	return p
+}
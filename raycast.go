@@ -0,0 +1,170 @@
+package shapes
+
+import "math"
+
+// RayHit describes where a ray hits a shape.
+type RayHit struct {
+	// Shape is the shape hit by the ray. Groups report the
+	// child shape.
+	Shape Shape
+
+	// X and Y are the world coordinates of the hit point.
+	X, Y float32
+
+	// Normal is the unit normal of the surface at the hit point,
+	// facing the origin of the ray.
+	Normal [2]float32
+
+	// Distance is the distance from the origin of the ray to the
+	// hit point.
+	Distance float32
+}
+
+// raycast casts a ray from (ox, oy) along the direction (dx, dy)
+// against the convex pieces of s, the same ones used for collision
+// detection. The direction doesn't need to be normalized. Hits
+// farther than maxDist are ignored. If the origin lies inside s, it's
+// hit at distance zero.
+func raycast(s Shape, ox, oy, dx, dy, maxDist float32) (RayHit, bool) {
+	l := float32(math.Hypot(float64(dx), float64(dy)))
+	if l == 0 || maxDist < 0 {
+		return RayHit{}, false
+	}
+	dx, dy = dx/l, dy/l
+
+	hit := RayHit{Distance: float32(math.Inf(1))}
+	for _, h := range hullsOf(s) {
+		if t, nx, ny, ok := h.raycast(ox, oy, dx, dy); ok && t <= maxDist && t < hit.Distance {
+			hit = RayHit{s, ox + dx*t, oy + dy*t, [2]float32{nx, ny}, t}
+		}
+	}
+	if hit.Shape == nil {
+		return RayHit{}, false
+	}
+	return hit, true
+}
+
+// raycast returns the distance along the unit direction (dx, dy)
+// from (ox, oy) to the nearest point of h and the normal of h there.
+func (h hull) raycast(ox, oy, dx, dy float32) (float32, float32, float32, bool) {
+	switch {
+	case h.radius > 0:
+		return raycastCircle(h.points[0], h.points[1], h.radius, ox, oy, dx, dy)
+	case len(h.points) == 4:
+		return raycastSegment(h.points, ox, oy, dx, dy)
+	case len(h.points) >= 6:
+		return raycastPolygon(h.points, ox, oy, dx, dy)
+	}
+	return 0, 0, 0, false
+}
+
+// raycastCircle intersects a ray with the circle of center (cx, cy).
+func raycastCircle(cx, cy, r, ox, oy, dx, dy float32) (float32, float32, float32, bool) {
+	mx, my := ox-cx, oy-cy
+	c := mx*mx + my*my - r*r
+	if c <= 0 {
+		return 0, -dx, -dy, true
+	}
+	b := mx*dx + my*dy
+	disc := b*b - c
+	if b > 0 || disc < 0 {
+		return 0, 0, 0, false
+	}
+	t := -b - float32(math.Sqrt(float64(disc)))
+	return t, (mx + dx*t) / r, (my + dy*t) / r, true
+}
+
+// raycastSegment intersects a ray with a segment.
+func raycastSegment(points []float32, ox, oy, dx, dy float32) (float32, float32, float32, bool) {
+	ax, ay, bx, by := points[0], points[1], points[2], points[3]
+	ex, ey := bx-ax, by-ay
+	den := dx*ey - dy*ex
+	if den == 0 {
+		// Parallel rays only hit a collinear segment
+		if cross(ax, ay, bx, by, ox, oy) != 0 {
+			return 0, 0, 0, false
+		}
+		ta, tb := (ax-ox)*dx+(ay-oy)*dy, (bx-ox)*dx+(by-oy)*dy
+		if ta < 0 && tb < 0 {
+			return 0, 0, 0, false
+		}
+		t := ta
+		if tb < t {
+			t = tb
+		}
+		if t < 0 {
+			t = 0
+		}
+		return t, -dx, -dy, true
+	}
+	wx, wy := ax-ox, ay-oy
+	t := (wx*ey - wy*ex) / den
+	u := (wx*dy - wy*dx) / den
+	if t < 0 || u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+	l := float32(math.Hypot(float64(ex), float64(ey)))
+	nx, ny := -ey/l, ex/l
+	if nx*dx+ny*dy > 0 {
+		nx, ny = -nx, -ny
+	}
+	return t, nx, ny, true
+}
+
+// raycastPolygon intersects a ray with a convex polygon, clipping the
+// ray against the half-planes of its edges.
+func raycastPolygon(points []float32, ox, oy, dx, dy float32) (float32, float32, float32, bool) {
+	// Outward normals depend on the orientation of the polygon
+	var area float32
+	n := len(points) / 2
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += points[i*2]*points[j*2+1] - points[j*2]*points[i*2+1]
+	}
+	if area == 0 {
+		return 0, 0, 0, false
+	}
+	sign := float32(1)
+	if area < 0 {
+		sign = -1
+	}
+
+	enter, exit := float32(0), float32(math.Inf(1))
+	var nx, ny float32
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		ex, ey := points[j*2]-points[i*2], points[j*2+1]-points[i*2+1]
+		l := float32(math.Hypot(float64(ex), float64(ey)))
+		if l == 0 {
+			continue
+		}
+		ux, uy := sign*ey/l, -sign*ex/l
+
+		// Signed distance of the origin from the edge and speed
+		// of the ray towards its outside
+		dist := (ox-points[i*2])*ux + (oy-points[i*2+1])*uy
+		speed := dx*ux + dy*uy
+		switch {
+		case speed == 0:
+			if dist > 0 {
+				return 0, 0, 0, false
+			}
+		case speed < 0:
+			if t := -dist / speed; t > enter {
+				enter, nx, ny = t, ux, uy
+			}
+		default:
+			if t := -dist / speed; t < exit {
+				exit = t
+			}
+		}
+		if enter > exit {
+			return 0, 0, 0, false
+		}
+	}
+	if nx == 0 && ny == 0 {
+		// The origin is inside the polygon
+		return 0, -dx, -dy, true
+	}
+	return enter, nx, ny, true
+}
@@ -0,0 +1,53 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/remogatto/shaders"
+)
+
+// NewRegularPolygon creates a new filled regular polygon built
+// around its center at (0, 0). It takes as arguments a linked
+// program (e.g. built from DefaultBoxVS and DefaultBoxFS), the number
+// of sides and the radius of the circumscribed circle. The first
+// vertex points upwards. The number of sides can't be less than 3.
+func NewRegularPolygon(program shaders.Program, sides int, radius float32) *Polygon {
+	if sides < 3 {
+		sides = 3
+	}
+	outline := make([]float32, 0, sides*2)
+	for i := 0; i < sides; i++ {
+		a := math.Pi/2 + 2*math.Pi*float64(i)/float64(sides)
+		outline = append(
+			outline,
+			radius*float32(math.Cos(a)),
+			radius*float32(math.Sin(a)),
+		)
+	}
+	return newPolygon(program, outline, 0, 0)
+}
+
+// NewStar creates a new filled star built around its center at
+// (0, 0). It takes as arguments a linked program, the number of
+// points of the star and the radii of the circles passing through
+// the inner and the outer vertices. The first point of the star
+// points upwards. The number of points can't be less than 2.
+func NewStar(program shaders.Program, points int, innerRadius, outerRadius float32) *Polygon {
+	if points < 2 {
+		points = 2
+	}
+	outline := make([]float32, 0, points*4)
+	for i := 0; i < points*2; i++ {
+		r := outerRadius
+		if i%2 == 1 {
+			r = innerRadius
+		}
+		a := math.Pi/2 + math.Pi*float64(i)/float64(points)
+		outline = append(
+			outline,
+			r*float32(math.Cos(a)),
+			r*float32(math.Sin(a)),
+		)
+	}
+	return newPolygon(program, outline, 0, 0)
+}
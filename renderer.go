@@ -0,0 +1,220 @@
+package shapes
+
+import (
+	"sort"
+
+	"github.com/remogatto/mathgl"
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+var (
+	// Synchronous makes drawing wait for the GPU to complete the
+	// rendering, as in previous versions: shapes drawn outside a
+	// frame are finished one by one and Renderer.End finishes the
+	// frame before returning. It's meant for callers reading back
+	// the pixels, like screenshot tests.
+	Synchronous = false
+)
+
+// current is the renderer whose frame is being built, if any.
+var current *Renderer
+
+// Renderer submits the shapes drawn in a frame to the GPU. Between
+// Begin and End, Draw, as well as the Draw method of the shapes, just
+// enqueues work. End sorts the queue by layer (see SetZ), then by
+// program and texture, so that consecutive shapes sharing program,
+// texture and matrices are merged in a single draw call, and flushes
+// the commands once. Shapes in the same layer are moved before the
+// ones drawn earlier only if they don't overlap them, so overlapping
+// shapes are always drawn in order. A renderer must be used from the
+// goroutine owning the OpenGL context and only one frame can be built
+// at a time.
+type Renderer struct {
+	batch *Batch
+
+	// Shapes drawn in the frame
+	queue []drawCommand
+
+	// Whether shapes in the same layer keep the drawing order
+	stable bool
+}
+
+// drawCommand is a shape enqueued in a frame, with its model matrix
+// and layer when it was drawn.
+type drawCommand struct {
+	base    *Base
+	model   mathgl.Mat4f
+	mode    gl.Enum
+	indices []uint16
+	z       float32
+
+	// Bounds of the shape in world coordinates
+	bounds Rect
+
+	// Position of the program and texture of the shape in the
+	// order they were first drawn in the frame
+	program, texture int
+}
+
+// NewRenderer returns a new renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{batch: NewBatch()}
+}
+
+// SetStableSort sets whether shapes in the same layer are drawn in
+// the order they were enqueued. Otherwise the ones not overlapping
+// are sorted by program and texture to reduce the draw calls.
+func (r *Renderer) SetStableSort(stable bool) {
+	r.stable = stable
+}
+
+// Begin starts a new frame.
+func (r *Renderer) Begin() {
+	current = r
+	r.queue = r.queue[:0]
+	r.batch.Begin()
+}
+
+// Draw enqueues the given shapes in the frame. It's the same as
+// calling their Draw method between Begin and End.
+func (r *Renderer) Draw(shapes ...Shape) {
+	for _, s := range shapes {
+		s.Draw()
+	}
+}
+
+// enqueue adds to the frame the vertices of b rendered with the given
+// primitive mode, listed by indices if not nil, and with the current
+// model matrix and layer of b.
+func (r *Renderer) enqueue(b *Base, mode gl.Enum, indices []uint16) {
+	r.queue = append(r.queue, drawCommand{
+		base:    b,
+		model:   b.worldMatrix(),
+		mode:    mode,
+		indices: indices,
+		z:       b.worldZ(),
+	})
+}
+
+// End sorts and submits the enqueued shapes and flushes the frame.
+// Each shape is placed as it was when drawn, so it can be drawn many
+// times in different places, but vertices and colors are the ones it
+// has when End is called. If Synchronous is true, it also waits for
+// the GPU to complete the rendering.
+func (r *Renderer) End() {
+	r.sort()
+	for _, c := range r.queue {
+		if !r.batch.add(c.base, c.model, c.mode, c.indices) {
+			c.base.submit(c.model, c.mode, c.indices)
+		}
+	}
+	r.batch.End()
+
+	// Don't keep the shapes alive until the next frame
+	for i := range r.queue {
+		r.queue[i] = drawCommand{}
+	}
+	r.queue = r.queue[:0]
+
+	if current == r {
+		current = nil
+	}
+	gl.Flush()
+	if Synchronous {
+		gl.Finish()
+	}
+}
+
+// sort sorts the queue by layer and, unless the sort is stable, by
+// program and texture within each layer.
+func (r *Renderer) sort() {
+	sort.SliceStable(r.queue, func(i, j int) bool { return r.queue[i].z < r.queue[j].z })
+	if r.stable {
+		return
+	}
+	programs := make(map[shaders.Program]int)
+	textures := make(map[uint32]int)
+	for i := range r.queue {
+		c := &r.queue[i]
+		p, ok := programs[c.base.program]
+		if !ok {
+			p = len(programs)
+			programs[c.base.program] = p
+		}
+		c.program = p
+		if c.base.hasTexCoords() {
+			t, ok := textures[c.base.texBuffer]
+			if !ok {
+				t = len(textures) + 1
+				textures[c.base.texBuffer] = t
+			}
+			c.texture = t
+		}
+		c.bounds = rectOf(appendTransformed(nil, c.base.vertices, c.model))
+	}
+	sorted := make([]drawCommand, 0, len(r.queue))
+	for start := 0; start < len(r.queue); {
+		end := start + 1
+		for end < len(r.queue) && r.queue[end].z == r.queue[start].z {
+			end++
+		}
+		sorted = appendRuns(sorted, r.queue[start:end])
+		start = end
+	}
+	copy(r.queue, sorted)
+}
+
+// appendRuns appends to sorted the commands of a layer, grouped in
+// runs sharing program and texture. A command joins the last run with
+// its program and texture only if it doesn't overlap the commands of
+// the runs after that one, otherwise it starts a new run.
+func appendRuns(sorted []drawCommand, layer []drawCommand) []drawCommand {
+	var runs [][]*drawCommand
+	for i := range layer {
+		c := &layer[i]
+		target := -1
+	search:
+		for j := len(runs) - 1; j >= 0; j-- {
+			if first := runs[j][0]; first.program == c.program && first.texture == c.texture {
+				target = j
+				break
+			}
+			for _, other := range runs[j] {
+				if other.bounds.Overlaps(c.bounds) {
+					break search
+				}
+			}
+		}
+		if target < 0 {
+			runs = append(runs, nil)
+			target = len(runs) - 1
+		}
+		runs[target] = append(runs[target], c)
+	}
+	for _, run := range runs {
+		for _, c := range run {
+			sorted = append(sorted, *c)
+		}
+	}
+	return sorted
+}
+
+// DrawCalls returns the number of draw calls issued in the frame.
+func (r *Renderer) DrawCalls() int {
+	return r.batch.DrawCalls()
+}
+
+// Release frees the GPU resources used to render the frames.
+func (r *Renderer) Release() {
+	r.batch.Release()
+}
+
+// finishDraw waits for the GPU to complete the rendering if
+// Synchronous is true and no frame is being built.
+func finishDraw() {
+	if Synchronous && current == nil {
+		gl.Flush()
+		gl.Finish()
+	}
+}
@@ -0,0 +1,167 @@
+package shapes
+
+import (
+	"math"
+
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// RoundedBox represents a box shape with rounded corners.
+type RoundedBox struct {
+	Base
+
+	// Size of the box
+	width, height float32
+
+	// Radius of the corners
+	radius float32
+
+	// Number of segments approximating each corner
+	cornerSegments int
+}
+
+// NewRoundedBox creates a new box with rounded corners. It takes as
+// arguments a linked program (e.g. built from DefaultBoxVS and
+// DefaultBoxFS), width and height values, the radius of the corners
+// and the number of segments approximating each corner. The radius
+// can't exceed half of the shortest side.
+func NewRoundedBox(program shaders.Program, width, height, radius float32, cornerSegments int) *RoundedBox {
+
+	box := new(RoundedBox)
+
+	if limit := float32(math.Min(float64(width), float64(height))) / 2; radius > limit {
+		radius = limit
+	}
+	if radius < 0 {
+		radius = 0
+	}
+	if cornerSegments < 1 {
+		cornerSegments = 1
+	}
+
+	box.width, box.height = width, height
+	box.radius = radius
+	box.cornerSegments = cornerSegments
+
+	// The box is built as a triangle fan around its center at
+	// (0, 0). Corners are listed counterclockwise starting from
+	// the top-right one.
+	corners := [4][2]float32{
+		{width/2 - radius, height/2 - radius},
+		{-width/2 + radius, height/2 - radius},
+		{-width/2 + radius, -height/2 + radius},
+		{width/2 - radius, -height/2 + radius},
+	}
+	box.vertices = make([]float32, 0, (4*(cornerSegments+1)+2)*2)
+	box.vertices = append(box.vertices, 0, 0)
+	for i, c := range corners {
+		for j := 0; j <= cornerSegments; j++ {
+			a := math.Pi / 2 * (float64(i) + float64(j)/float64(cornerSegments))
+			box.vertices = append(
+				box.vertices,
+				c[0]+radius*float32(math.Cos(a)),
+				c[1]+radius*float32(math.Sin(a)),
+			)
+		}
+	}
+	// Close the fan
+	box.vertices = append(box.vertices, box.vertices[2], box.vertices[3])
+
+	// Set the default color
+	box.SetColor(DefaultColor)
+
+	box.initTexturedProgram(program)
+
+	// Start with no rotation and unit scale.
+	box.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	box.setLocalBounds(-width/2, -height/2, width/2, height/2)
+
+	return box
+}
+
+// Radius returns the radius of the corners.
+func (box *RoundedBox) Radius() float32 {
+	return box.radius
+}
+
+// SetTexture sets a texture for the box. Like for Box, texCoords
+// contains the texture coordinates of the bottom-left, bottom-right,
+// top-left and top-right corners of the enclosing rectangle. The
+// coordinates of each vertex of the rounded outline are interpolated
+// from them. If texCoords is nil the whole texture is mapped on the
+// box, as done by Circle and Ellipse: unlike Box, the box is never
+// left without texture coordinates.
+func (box *RoundedBox) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = []float32{
+			0, 0,
+			1, 0,
+			0, 1,
+			1, 1,
+		}
+	}
+	return box.Base.SetTexture(texture, box.interpolateTexCoords(texCoords))
+}
+
+// interpolateTexCoords bilinearly interpolates the texture
+// coordinates of each vertex from the coordinates of the four
+// corners of the enclosing rectangle.
+func (box *RoundedBox) interpolateTexCoords(corners []float32) []float32 {
+	if len(corners) < 8 {
+		return nil
+	}
+	texCoords := make([]float32, len(box.vertices))
+	for i := 0; i < len(box.vertices); i += 2 {
+		// A box without width or height maps the middle of
+		// the texture along that axis
+		s, t := float32(0.5), float32(0.5)
+		if box.width != 0 {
+			s += box.vertices[i] / box.width
+		}
+		if box.height != 0 {
+			t += box.vertices[i+1] / box.height
+		}
+		for k := 0; k < 2; k++ {
+			bottom := corners[k]*(1-s) + corners[2+k]*s
+			top := corners[4+k]*(1-s) + corners[6+k]*s
+			texCoords[i+k] = bottom*(1-t) + top*t
+		}
+	}
+	return texCoords
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the box.
+func (box *RoundedBox) Contains(x, y float32) bool {
+	return box.containsLocal(gl.TRIANGLE_FAN, nil, x, y)
+}
+
+// hulls returns the outline of the box as a single convex piece.
+func (box *RoundedBox) hulls() []hull {
+	return box.outlineHull(box.vertices[2:])
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the box within
+// maxDist.
+func (box *RoundedBox) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(box, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the box on the surface.
+func (box *RoundedBox) Draw() {
+	box.drawArrays(gl.TRIANGLE_FAN)
+}
+
+// Clone makes a copy of the box.
+func (box *RoundedBox) Clone() Shape {
+	b := NewRoundedBox(box.program, box.width, box.height, box.radius, box.cornerSegments)
+	b.copyColors(&box.Base)
+	if len(box.texCoords) > 0 {
+		b.Base.SetTexture(box.texBuffer, box.texCoords)
+	}
+	return b
+}
@@ -1,9 +1,6 @@
 package shapes
 
 import (
-	"image"
-
-	"github.com/remogatto/mathgl"
 	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shaders"
 )
@@ -33,12 +30,19 @@ var (
                  }`)
 )
 
+// Segment implements Shape.
+var _ Shape = (*Segment)(nil)
+
 // Segment is a structure representing a segment.
 type Segment struct {
 	Base
 
 	// Points of the segment
 	x1, y1, x2, y2 float32
+
+	// Stroke style
+	width float32
+	cap   Cap
 }
 
 // NewSegment returns a new segment object. It takes a program
@@ -52,54 +56,103 @@ func NewSegment(program shaders.Program, x1, y1, x2, y2 float32) *Segment {
 	segment.x1, segment.x2 = x1, x2
 	segment.y1, segment.y2 = y1, y2
 
-	segment.vertices = []float32{
-		segment.x1, segment.y1,
-		segment.x2, segment.y2,
-	}
-
 	// Set the default color
-	segment.SetColor(DefaultColor)
+	segment.color = DefaultColor
+	segment.tessellate()
 
-	// Size of the segment bounding box
-	// segment.w = float32(math.Abs(float64(x1 - x2)))
-	// segment.h = float32(math.Abs(float64(y1 - y2)))
-	segment.bounds = image.Rect(int(x1), int(y1), int(x2), int(y2))
-
-	// Center of the segment
+	// Center of the segment. The vertices are not relative to it,
+	// so it's also the pivot of rotation and scale.
 	segment.x = (segment.x1 + segment.x2) / 2
 	segment.y = (segment.y1 + segment.y2) / 2
+	segment.px, segment.py = segment.x, segment.y
 
-	segment.program = program
-	segment.program.Use()
-
-	// Get variables IDs from shaders
-	segment.posId = segment.program.GetAttribute("pos")
-	segment.colorId = segment.program.GetAttribute("color")
-	segment.projMatrixId = segment.program.GetUniform("projection")
-	segment.modelMatrixId = segment.program.GetUniform("model")
-	segment.viewMatrixId = segment.program.GetUniform("view")
+	segment.initProgram(program)
 
-	// Fill the model matrix with the identity.
-	segment.modelMatrix = mathgl.Ident4f()
+	// Start with no rotation and unit scale.
+	segment.initTransform()
 
 	return segment
 }
 
-// Draw actually renders the segment on the surface.
-func (segment *Segment) Draw() {
-	segment.program.Use()
-	gl.VertexAttribPointer(segment.posId, 2, gl.FLOAT, false, 0, &segment.vertices[0])
-	gl.EnableVertexAttribArray(segment.posId)
+// tessellate builds the vertices of the segment and updates its
+// colors and bounds accordingly. A segment with zero width is
+// rendered as a line primitive, otherwise it's tessellated in a
+// triangle strip like Polyline.
+func (segment *Segment) tessellate() {
+	points := []float32{
+		segment.x1, segment.y1,
+		segment.x2, segment.y2,
+	}
+	segment.vertices = nil
+	if segment.width > 0 {
+		segment.vertices = stroke(points, segment.width, MiterJoin, segment.cap, DefaultMiterLimit)
+	}
+	if segment.vertices == nil {
+		segment.vertices = points
+	}
+	segment.updateColors()
+	segment.updateBounds()
+}
 
-	gl.VertexAttribPointer(segment.colorId, 4, gl.FLOAT, false, 0, &segment.vColor[0])
-	gl.EnableVertexAttribArray(segment.colorId)
+// Width returns the stroke width of the segment.
+func (segment *Segment) Width() float32 {
+	return segment.width
+}
+
+// SetWidth sets the stroke width of the segment. A zero width
+// renders the segment as a line primitive.
+func (segment *Segment) SetWidth(width float32) {
+	segment.width = width
+	segment.tessellate()
+}
+
+// SetCap sets the style used to terminate the ends of a segment
+// with non-zero width.
+func (segment *Segment) SetCap(c Cap) {
+	segment.cap = c
+	segment.tessellate()
+}
+
+// Contains returns true if (x, y), in world coordinates, lies on the
+// stroke of the segment or within DefaultHitTolerance from it.
+func (segment *Segment) Contains(x, y float32) bool {
+	if len(segment.vertices) > 4 && segment.containsLocal(gl.TRIANGLE_STRIP, nil, x, y) {
+		return true
+	}
+	return segment.nearLine([]float32{segment.x1, segment.y1, segment.x2, segment.y2}, x, y)
+}
 
-	gl.UniformMatrix4fv(int32(segment.modelMatrixId), 1, false, (*float32)(&segment.modelMatrix[0]))
-	gl.UniformMatrix4fv(int32(segment.projMatrixId), 1, false, (*float32)(&segment.projMatrix[0]))
-	gl.UniformMatrix4fv(int32(segment.viewMatrixId), 1, false, (*float32)(&segment.viewMatrix[0]))
+// hulls returns the triangles of the stroke of the segment or the
+// segment itself if it has no width.
+func (segment *Segment) hulls() []hull {
+	if len(segment.vertices) > 4 {
+		return segment.triangleHulls(gl.TRIANGLE_STRIP, nil)
+	}
+	return segment.lineHulls([]float32{segment.x1, segment.y1, segment.x2, segment.y2})
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the segment within
+// maxDist.
+func (segment *Segment) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(segment, originX, originY, dirX, dirY, maxDist)
+}
 
-	gl.DrawArrays(gl.LINES, 0, 2)
+// Clone makes a copy of the segment.
+func (segment *Segment) Clone() Shape {
+	s := NewSegment(segment.program, segment.x1, segment.y1, segment.x2, segment.y2)
+	s.width, s.cap = segment.width, segment.cap
+	s.tessellate()
+	s.copyColors(&segment.Base)
+	return s
+}
 
-	gl.Flush()
-	gl.Finish()
+// Draw actually renders the segment on the surface.
+func (segment *Segment) Draw() {
+	// A tessellated segment has more than two vertices
+	if len(segment.vertices) > 4 {
+		segment.drawArrays(gl.TRIANGLE_STRIP)
+	} else {
+		segment.drawArrays(gl.LINES)
+	}
 }
@@ -1,8 +1,9 @@
 package shapes
 
 import (
-	"image"
 	"image/color"
+
+	"github.com/remogatto/mathgl"
 )
 
 var (
@@ -22,12 +23,29 @@ type Shape interface {
 	// Scale scales the shape by (sx,sy) factor.
 	Scale(sx, sy float32)
 
+	// Skew skews the shape by the given angles in degrees along
+	// the x and y axes.
+	Skew(ax, ay float32)
+
+	// Transform returns the model matrix of the shape.
+	Transform() mathgl.Mat4f
+
+	// SetTransform sets the model matrix of the shape.
+	SetTransform(m mathgl.Mat4f)
+
 	// Move moves the shape by (dx, dy).
 	Move(dx, dy float32)
 
 	// MoveTo moves the (center of the) shape in position (x,y).
 	MoveTo(x, y float32)
 
+	// SetZ sets the layer of the shape, relative to the group
+	// containing it. Shapes with a lower z are drawn first.
+	SetZ(z float32)
+
+	// Z returns the layer of the shape.
+	Z() float32
+
 	// Draw renders the shape on the surface.
 	Draw()
 
@@ -41,7 +59,28 @@ type Shape interface {
 	Angle() float32
 
 	// Bounds returns the bounding rectangle of the shape.
-	Bounds() image.Rectangle
+	Bounds() Rect
+
+	// Contains returns true if the point (x, y), in world
+	// coordinates, lies inside the shape.
+	Contains(x, y float32) bool
+
+	// Raycast returns where the ray from (originX, originY) along
+	// (dirX, dirY), in world coordinates, first hits the shape
+	// within maxDist.
+	Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool)
+
+	// WorldVertices returns the vertices of the shape in world
+	// coordinates.
+	WorldVertices() []float32
+
+	// AABB returns the axis-aligned bounding rectangle of the
+	// shape in world coordinates.
+	AABB() Rect
+
+	// OBB returns the oriented bounding box of the shape in world
+	// coordinates.
+	OBB() OBB
 
 	// String returns a string representation of the shape.
 	String() string
@@ -49,6 +88,10 @@ type Shape interface {
 	// AttachToWorld attaches the shape to a world.
 	AttachToWorld(world World)
 
+	// Release frees the GPU resources of the shape. They are
+	// allocated again if the shape is drawn afterwards.
+	Release()
+
 	// Clone clones the current shape and returns a new shape.
 	Clone() Shape
 
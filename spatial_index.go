@@ -0,0 +1,370 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// SpatialIndex is a uniform grid of square cells indexing shapes by
+// their axis-aligned bounding box in world coordinates, so that the
+// shapes in a region can be found without testing all of them. The
+// index is updated automatically when an indexed shape, or a group
+// containing it, is moved, rotated, scaled or resized.
+type SpatialIndex struct {
+	// mutex handles concurrent access to the index
+	mutex sync.Mutex
+
+	// Size of the cells
+	cellSize float32
+
+	// Entries overlapping each cell
+	cells map[cell][]*indexEntry
+
+	// Entries of the indexed shapes
+	entries map[Shape]*indexEntry
+
+	// Range of the occupied cells. It's recomputed lazily when
+	// stale, that is when an entry on its border was moved or
+	// removed.
+	extent      cellRange
+	hasExtent   bool
+	extentStale bool
+
+	// Insertion counter, used to sort the results
+	order int
+
+	// Query counter, used to visit each entry once per query
+	mark int
+}
+
+// cell is the position of a cell in the grid.
+type cell struct {
+	x, y int
+}
+
+// cellRange is a rectangle of cells, bounds included.
+type cellRange struct {
+	min, max cell
+}
+
+// indexEntry holds an indexed shape and the cells it overlaps.
+type indexEntry struct {
+	index  *SpatialIndex
+	shape  Shape
+	bounds Rect
+	cells  cellRange
+	order  int
+	mark   int
+}
+
+// observable is implemented by the shapes notifying when their
+// bounds in world coordinates may have changed.
+type observable interface {
+	addObserver(o boundsObserver)
+	removeObserver(o boundsObserver)
+}
+
+// NewSpatialIndex returns an empty index whose cells have the given
+// size. Cells about as big as the typical indexed shape give the best
+// performance.
+func NewSpatialIndex(cellSize float32) *SpatialIndex {
+	if cellSize <= 0 {
+		cellSize = 1
+	}
+	return &SpatialIndex{
+		cellSize: cellSize,
+		cells:    make(map[cell][]*indexEntry),
+		entries:  make(map[Shape]*indexEntry),
+	}
+}
+
+// Len returns the number of indexed shapes.
+func (idx *SpatialIndex) Len() int {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	return len(idx.entries)
+}
+
+// Insert adds s to the index. Inserting a shape already in the index
+// updates its position.
+func (idx *SpatialIndex) Insert(s Shape) {
+	idx.mutex.Lock()
+	e, ok := idx.entries[s]
+	if !ok {
+		e = &indexEntry{index: idx, shape: s, order: idx.order}
+		idx.order++
+		idx.entries[s] = e
+	} else {
+		idx.unlink(e)
+	}
+	idx.link(e)
+	idx.mutex.Unlock()
+
+	if o, isObservable := s.(observable); isObservable && !ok {
+		o.addObserver(e)
+	}
+}
+
+// Remove removes s from the index. It does nothing if s is not in
+// the index.
+func (idx *SpatialIndex) Remove(s Shape) {
+	idx.mutex.Lock()
+	e, ok := idx.entries[s]
+	if ok {
+		idx.unlink(e)
+		delete(idx.entries, s)
+	}
+	idx.mutex.Unlock()
+
+	if o, isObservable := s.(observable); ok && isObservable {
+		o.removeObserver(e)
+	}
+}
+
+// Update moves s in the cells overlapped by its current bounds.
+// Indexed shapes are updated automatically, so this is only needed
+// for shapes implemented outside this package.
+func (idx *SpatialIndex) Update(s Shape) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if e, ok := idx.entries[s]; ok {
+		idx.unlink(e)
+		idx.link(e)
+	}
+}
+
+// boundsChanged updates the cells of the entry.
+func (e *indexEntry) boundsChanged() {
+	e.index.Update(e.shape)
+}
+
+// Query returns the shapes whose bounding box in world coordinates
+// overlaps r, in the order they were inserted.
+func (idx *SpatialIndex) Query(r Rect) []Shape {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	var found []*indexEntry
+	idx.visit(idx.cellsOf(r), func(e *indexEntry) {
+		if e.bounds.Overlaps(r) {
+			found = append(found, e)
+		}
+	})
+	return sortedShapes(found)
+}
+
+// QueryPoint returns the shapes containing (x, y), in world
+// coordinates, in the order they were inserted.
+func (idx *SpatialIndex) QueryPoint(x, y float32) []Shape {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	var found []*indexEntry
+	idx.visit(idx.cellsOf(Rect{x, y, x, y}), func(e *indexEntry) {
+		if e.bounds.Contains(x, y) && e.shape.Contains(x, y) {
+			found = append(found, e)
+		}
+	})
+	return sortedShapes(found)
+}
+
+// Nearest returns the shape whose bounding box in world coordinates
+// is the nearest to (x, y). On ties the shape inserted first wins. It
+// returns false if the index is empty.
+func (idx *SpatialIndex) Nearest(x, y float32) (Shape, bool) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if len(idx.entries) == 0 {
+		return nil, false
+	}
+	idx.updateExtent()
+
+	var (
+		best     *indexEntry
+		bestDist = math.Inf(1)
+	)
+	consider := func(e *indexEntry) {
+		d := distanceToRect(e.bounds, x, y)
+		if d < bestDist || d == bestDist && e.order < best.order {
+			best, bestDist = e, d
+		}
+	}
+
+	// Visit the rings of cells around the one containing the
+	// point until no unvisited shape can be nearer than the best
+	// one found. Rings not reaching the occupied cells are
+	// skipped and only the occupied part of each ring is visited.
+	c := idx.cellAt(x, y)
+	start := maxInt(
+		maxInt(idx.extent.min.x-c.x, c.x-idx.extent.max.x),
+		maxInt(idx.extent.min.y-c.y, c.y-idx.extent.max.y),
+	)
+	idx.mark++
+	for r := maxInt(start, 0); ; r++ {
+		ring := cellRange{cell{c.x - r, c.y - r}, cell{c.x + r, c.y + r}}
+		area := ring.intersect(idx.extent)
+		for cx := area.min.x; cx <= area.max.x; cx++ {
+			for cy := area.min.y; cy <= area.max.y; cy++ {
+				if cx != ring.min.x && cx != ring.max.x && cy != ring.min.y && cy != ring.max.y {
+					cy = ring.max.y - 1
+					continue
+				}
+				for _, e := range idx.cells[cell{cx, cy}] {
+					if e.mark != idx.mark {
+						e.mark = idx.mark
+						consider(e)
+					}
+				}
+			}
+		}
+		if best != nil && bestDist <= float64(r)*float64(idx.cellSize) || ring.contains(idx.extent) {
+			break
+		}
+	}
+	return best.shape, true
+}
+
+// link adds the entry to the cells overlapped by the bounds of its
+// shape.
+func (idx *SpatialIndex) link(e *indexEntry) {
+	e.bounds = e.shape.AABB()
+	e.cells = idx.cellsOf(e.bounds)
+	for cx := e.cells.min.x; cx <= e.cells.max.x; cx++ {
+		for cy := e.cells.min.y; cy <= e.cells.max.y; cy++ {
+			c := cell{cx, cy}
+			idx.cells[c] = append(idx.cells[c], e)
+		}
+	}
+	idx.growExtent(e.cells)
+}
+
+// unlink removes the entry from the cells it overlaps.
+func (idx *SpatialIndex) unlink(e *indexEntry) {
+	c := e.cells
+	if c.min.x <= idx.extent.min.x || c.min.y <= idx.extent.min.y || c.max.x >= idx.extent.max.x || c.max.y >= idx.extent.max.y {
+		idx.extentStale = true
+	}
+	for cx := e.cells.min.x; cx <= e.cells.max.x; cx++ {
+		for cy := e.cells.min.y; cy <= e.cells.max.y; cy++ {
+			c := cell{cx, cy}
+			entries := idx.cells[c]
+			for i, other := range entries {
+				if other == e {
+					entries = append(entries[:i], entries[i+1:]...)
+					break
+				}
+			}
+			if len(entries) == 0 {
+				delete(idx.cells, c)
+			} else {
+				idx.cells[c] = entries
+			}
+		}
+	}
+}
+
+// growExtent adds the given cells to the range of the occupied ones.
+func (idx *SpatialIndex) growExtent(cells cellRange) {
+	if !idx.hasExtent {
+		idx.extent, idx.hasExtent = cells, true
+	} else {
+		idx.extent = idx.extent.union(cells)
+	}
+}
+
+// updateExtent recomputes the range of the occupied cells if it's
+// stale.
+func (idx *SpatialIndex) updateExtent() {
+	if !idx.extentStale {
+		return
+	}
+	idx.extentStale = false
+	idx.extent, idx.hasExtent = cellRange{cell{0, 0}, cell{-1, -1}}, false
+	for _, e := range idx.entries {
+		idx.growExtent(e.cells)
+	}
+}
+
+// visit calls f once for each entry in the given cells.
+func (idx *SpatialIndex) visit(cells cellRange, f func(e *indexEntry)) {
+	idx.updateExtent()
+	idx.mark++
+	cells = cells.intersect(idx.extent)
+	for cx := cells.min.x; cx <= cells.max.x; cx++ {
+		for cy := cells.min.y; cy <= cells.max.y; cy++ {
+			for _, e := range idx.cells[cell{cx, cy}] {
+				if e.mark != idx.mark {
+					e.mark = idx.mark
+					f(e)
+				}
+			}
+		}
+	}
+}
+
+// cellAt returns the cell containing (x, y).
+func (idx *SpatialIndex) cellAt(x, y float32) cell {
+	return cell{
+		int(math.Floor(float64(x / idx.cellSize))),
+		int(math.Floor(float64(y / idx.cellSize))),
+	}
+}
+
+// cellsOf returns the range of cells overlapped by r.
+func (idx *SpatialIndex) cellsOf(r Rect) cellRange {
+	return cellRange{idx.cellAt(r.MinX, r.MinY), idx.cellAt(r.MaxX, r.MaxY)}
+}
+
+// union returns the smallest range containing r and s.
+func (r cellRange) union(s cellRange) cellRange {
+	return cellRange{
+		cell{minInt(r.min.x, s.min.x), minInt(r.min.y, s.min.y)},
+		cell{maxInt(r.max.x, s.max.x), maxInt(r.max.y, s.max.y)},
+	}
+}
+
+// intersect returns the cells in both r and s. The range is empty,
+// that is min is greater than max, if they don't overlap.
+func (r cellRange) intersect(s cellRange) cellRange {
+	return cellRange{
+		cell{maxInt(r.min.x, s.min.x), maxInt(r.min.y, s.min.y)},
+		cell{minInt(r.max.x, s.max.x), minInt(r.max.y, s.max.y)},
+	}
+}
+
+// contains reports whether s is inside r.
+func (r cellRange) contains(s cellRange) bool {
+	return r.min.x <= s.min.x && r.min.y <= s.min.y && s.max.x <= r.max.x && s.max.y <= r.max.y
+}
+
+// distanceToRect returns the distance from (x, y) to the nearest
+// point of r, zero if r contains the point.
+func distanceToRect(r Rect, x, y float32) float64 {
+	dx := math.Max(0, math.Max(float64(r.MinX-x), float64(x-r.MaxX)))
+	dy := math.Max(0, math.Max(float64(r.MinY-y), float64(y-r.MaxY)))
+	return math.Hypot(dx, dy)
+}
+
+// sortedShapes returns the shapes of the entries in the order they
+// were inserted.
+func sortedShapes(entries []*indexEntry) []Shape {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].order < entries[j].order })
+	shapes := make([]Shape, len(entries))
+	for i, e := range entries {
+		shapes[i] = e.shape
+	}
+	return shapes
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
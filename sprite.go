@@ -0,0 +1,240 @@
+package shapes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/remogatto/shaders"
+)
+
+// SpriteSheet describes a texture containing a grid of equally
+// sized frames. Frames are numbered row by row starting from the
+// top-left one.
+type SpriteSheet struct {
+	// Texture is the value returned by the OpenGL context for
+	// the sheet image.
+	Texture uint32
+
+	// Size of a frame in pixels
+	FrameWidth, FrameHeight int
+
+	// Size of the grid
+	Rows, Cols int
+}
+
+// Animation is a sequence of frames of a sprite sheet.
+type Animation struct {
+	// Frames is the sequence of frame indices.
+	Frames []int
+
+	// FPS is the number of frames shown per second.
+	FPS float32
+
+	// Loop restarts the animation when it reaches the end.
+	Loop bool
+}
+
+// Sprite is a box textured with a frame of a sprite sheet.
+type Sprite struct {
+	Box
+
+	sheet SpriteSheet
+
+	// Current frame of the sheet
+	frame int
+
+	// Named animations
+	animations map[string]Animation
+
+	// Animation being played
+	animation     Animation
+	animationName string
+	animationStep int
+	elapsed       time.Duration
+	playing       bool
+
+	// Whether the frame is mirrored
+	flipX, flipY bool
+}
+
+// NewSprite creates a new sprite showing the first frame of the
+// given sheet. It takes as arguments a linked program (e.g. built
+// from DefaultBoxVS and DefaultBoxFS) and the sheet description. The
+// size of the sprite is the size of a frame.
+func NewSprite(program shaders.Program, sheet SpriteSheet) *Sprite {
+	sprite := &Sprite{
+		Box:        *NewBox(program, float32(sheet.FrameWidth), float32(sheet.FrameHeight)),
+		sheet:      sheet,
+		animations: make(map[string]Animation),
+	}
+	sprite.texBuffer = sheet.Texture
+	sprite.texCoords = make([]float32, 8)
+	sprite.updateTexCoords()
+	return sprite
+}
+
+// Sheet returns the sprite sheet description.
+func (sprite *Sprite) Sheet() SpriteSheet {
+	return sprite.sheet
+}
+
+// FrameCount returns the number of frames in the sheet.
+func (sprite *Sprite) FrameCount() int {
+	return sprite.sheet.Rows * sprite.sheet.Cols
+}
+
+// Frame returns the index of the frame currently shown.
+func (sprite *Sprite) Frame() int {
+	return sprite.frame
+}
+
+// SetFrame shows the frame with the given index. Indices out of
+// range are wrapped around.
+func (sprite *Sprite) SetFrame(i int) {
+	if n := sprite.FrameCount(); n > 0 {
+		i %= n
+		if i < 0 {
+			i += n
+		}
+	}
+	sprite.frame = i
+	sprite.updateTexCoords()
+}
+
+// SetFlipX mirrors the frame horizontally, e.g. to make a character
+// face the other direction.
+func (sprite *Sprite) SetFlipX(flip bool) {
+	sprite.flipX = flip
+	sprite.updateTexCoords()
+}
+
+// SetFlipY mirrors the frame vertically.
+func (sprite *Sprite) SetFlipY(flip bool) {
+	sprite.flipY = flip
+	sprite.updateTexCoords()
+}
+
+// SetTexture replaces the texture of the sprite sheet, e.g. to use a
+// differently colored sheet with the same layout. The texture
+// coordinates are computed from the current frame, so texCoords is
+// ignored.
+func (sprite *Sprite) SetTexture(texture uint32, texCoords []float32) error {
+	sprite.sheet.Texture = texture
+	sprite.texBuffer = texture
+	sprite.markStale(texCoordBuffer)
+	return nil
+}
+
+// updateTexCoords maps the current frame on the box.
+func (sprite *Sprite) updateTexCoords() {
+	rows, cols := sprite.sheet.Rows, sprite.sheet.Cols
+	if rows <= 0 || cols <= 0 {
+		return
+	}
+	row, col := sprite.frame/cols, sprite.frame%cols
+
+	// The texture is flipped vertically by the shader, so the
+	// top row of the sheet has the highest v coordinates.
+	u0, u1 := float32(col)/float32(cols), float32(col+1)/float32(cols)
+	vb, vt := 1-float32(row+1)/float32(rows), 1-float32(row)/float32(rows)
+	if sprite.flipX {
+		u0, u1 = u1, u0
+	}
+	if sprite.flipY {
+		vb, vt = vt, vb
+	}
+
+	// Same order of the box vertices: bottom-left, bottom-right,
+	// top-left, top-right.
+	copy(sprite.texCoords, []float32{
+		u0, vb,
+		u1, vb,
+		u0, vt,
+		u1, vt,
+	})
+	sprite.markStale(texCoordBuffer)
+}
+
+// AddAnimation adds a named animation to the sprite.
+func (sprite *Sprite) AddAnimation(name string, animation Animation) {
+	sprite.animations[name] = animation
+}
+
+// Play starts playing the named animation from its first frame. It
+// returns an error if no animation was added with that name.
+func (sprite *Sprite) Play(name string) error {
+	animation, ok := sprite.animations[name]
+	if !ok {
+		return fmt.Errorf("cannot find an animation named '%s'", name)
+	}
+	sprite.animation = animation
+	sprite.animationName = name
+	sprite.animationStep = 0
+	sprite.elapsed = 0
+	sprite.playing = len(animation.Frames) > 0
+	if sprite.playing {
+		sprite.SetFrame(animation.Frames[0])
+	}
+	return nil
+}
+
+// Stop stops the animation, leaving the current frame shown.
+func (sprite *Sprite) Stop() {
+	sprite.playing = false
+}
+
+// Playing returns the name of the animation being played and whether
+// it's still running.
+func (sprite *Sprite) Playing() (string, bool) {
+	return sprite.animationName, sprite.playing
+}
+
+// Update advances the animation being played by dt.
+func (sprite *Sprite) Update(dt time.Duration) {
+	if !sprite.playing || sprite.animation.FPS <= 0 {
+		return
+	}
+	frameDuration := time.Duration(float64(time.Second) / float64(sprite.animation.FPS))
+	if frameDuration <= 0 {
+		return
+	}
+	sprite.elapsed += dt
+	frames := sprite.animation.Frames
+	step := sprite.animationStep
+	for sprite.elapsed >= frameDuration {
+		sprite.elapsed -= frameDuration
+		step++
+		if step >= len(frames) {
+			if !sprite.animation.Loop {
+				step = len(frames) - 1
+				sprite.playing = false
+				sprite.elapsed = 0
+				break
+			}
+			step = 0
+		}
+	}
+	if step != sprite.animationStep {
+		sprite.animationStep = step
+		sprite.SetFrame(frames[step])
+	}
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the sprite within
+// maxDist.
+func (sprite *Sprite) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(sprite, originX, originY, dirX, dirY, maxDist)
+}
+
+// Clone makes a copy of the sprite, including its animations.
+func (sprite *Sprite) Clone() Shape {
+	s := NewSprite(sprite.program, sprite.sheet)
+	s.copyColors(&sprite.Base)
+	for name, animation := range sprite.animations {
+		s.animations[name] = animation
+	}
+	s.flipX, s.flipY = sprite.flipX, sprite.flipY
+	s.SetFrame(sprite.frame)
+	return s
+}
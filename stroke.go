@@ -0,0 +1,235 @@
+package shapes
+
+import "math"
+
+// Join is the style used to join two consecutive segments of a
+// stroke.
+type Join int
+
+const (
+	// MiterJoin extends the outer edges of the segments until
+	// they meet. It falls back to BevelJoin when the miter
+	// length exceeds the miter limit.
+	MiterJoin Join = iota
+
+	// BevelJoin connects the outer corners of the segments with
+	// a straight edge.
+	BevelJoin
+
+	// RoundJoin connects the outer corners of the segments with
+	// an arc.
+	RoundJoin
+)
+
+// Cap is the style used to terminate the ends of a stroke.
+type Cap int
+
+const (
+	// ButtCap terminates the stroke exactly at its end points.
+	ButtCap Cap = iota
+
+	// SquareCap extends the stroke beyond its end points by half
+	// of its width.
+	SquareCap
+
+	// RoundCap terminates the stroke with a half circle.
+	RoundCap
+)
+
+var (
+	// DefaultMiterLimit is the default limit of the ratio between
+	// the miter length and the stroke width.
+	DefaultMiterLimit float32 = 4
+)
+
+// roundStep is the maximum angle in radians spanned by a single
+// triangle of round joins and caps.
+const roundStep = math.Pi / 12
+
+// stroke tessellates the polyline described by the given (x, y)
+// points in a triangle strip of the given width. Disconnected parts
+// of the strip (segments, joins and caps) are stitched together
+// using degenerate triangles. It returns nil if the polyline has
+// less than two distinct points.
+func stroke(points []float32, width float32, join Join, endCap Cap, miterLimit float32) []float32 {
+	return tessellateStroke(points, width, join, endCap, miterLimit, false)
+}
+
+// strokeClosed is like stroke but the last point of the polyline is
+// joined to the first one.
+func strokeClosed(points []float32, width float32, join Join, miterLimit float32) []float32 {
+	return tessellateStroke(points, width, join, ButtCap, miterLimit, true)
+}
+
+// tessellateStroke implements stroke and strokeClosed.
+func tessellateStroke(points []float32, width float32, join Join, endCap Cap, miterLimit float32, closed bool) []float32 {
+	// Remove consecutive duplicated points
+	pts := make([]float32, 0, len(points)+2)
+	for i := 0; i+1 < len(points); i += 2 {
+		n := len(pts)
+		if n > 0 && pts[n-2] == points[i] && pts[n-1] == points[i+1] {
+			continue
+		}
+		pts = append(pts, points[i], points[i+1])
+	}
+
+	// A closed polyline ends where it starts
+	if n := len(pts); closed && n >= 4 {
+		if pts[0] != pts[n-2] || pts[1] != pts[n-1] {
+			pts = append(pts, pts[0], pts[1])
+		}
+	}
+
+	n := len(pts) / 2
+	if n < 2 || width <= 0 {
+		return nil
+	}
+
+	hw := width / 2
+
+	// Unit direction of each segment
+	dirs := make([]float32, (n-1)*2)
+	for i := 0; i < n-1; i++ {
+		dx, dy := pts[i*2+2]-pts[i*2], pts[i*2+3]-pts[i*2+1]
+		l := float32(math.Hypot(float64(dx), float64(dy)))
+		dirs[i*2], dirs[i*2+1] = dx/l, dy/l
+	}
+
+	var strip []float32
+
+	x0, y0 := pts[0], pts[1]
+	dx0, dy0 := dirs[0], dirs[1]
+	xn, yn := pts[n*2-2], pts[n*2-1]
+	dxn, dyn := dirs[n*2-4], dirs[n*2-3]
+
+	// Closed polylines have no caps but an additional joint
+	// between the last and the first segment.
+	if closed {
+		endCap = ButtCap
+		strip = appendStrip(strip, joint(x0, y0, dxn, dyn, dx0, dy0, hw, join, miterLimit))
+	}
+
+	// Start cap
+	switch endCap {
+	case SquareCap:
+		pts[0], pts[1] = x0-dx0*hw, y0-dy0*hw
+	case RoundCap:
+		strip = appendStrip(strip, fan(x0, y0, hw, angleOf(-dy0, dx0), math.Pi))
+	}
+
+	// End cap
+	if endCap == SquareCap {
+		pts[n*2-2], pts[n*2-1] = xn+dxn*hw, yn+dyn*hw
+	}
+
+	for i := 0; i < n-1; i++ {
+		// The left normal of the segment is (-dy, dx)
+		dx, dy := dirs[i*2], dirs[i*2+1]
+		ax, ay := pts[i*2], pts[i*2+1]
+		bx, by := pts[i*2+2], pts[i*2+3]
+		strip = appendStrip(strip, []float32{
+			ax - dy*hw, ay + dx*hw,
+			ax + dy*hw, ay - dx*hw,
+			bx - dy*hw, by + dx*hw,
+			bx + dy*hw, by - dx*hw,
+		})
+
+		if i < n-2 {
+			strip = appendStrip(strip, joint(bx, by, dx, dy, dirs[i*2+2], dirs[i*2+3], hw, join, miterLimit))
+		}
+	}
+
+	if endCap == RoundCap {
+		strip = appendStrip(strip, fan(xn, yn, hw, angleOf(dyn, -dxn), math.Pi))
+	}
+
+	return strip
+}
+
+// joint returns the triangle strip filling the outer gap between two
+// segments meeting at (x, y) with unit directions (dx0, dy0) and
+// (dx1, dy1).
+func joint(x, y, dx0, dy0, dx1, dy1, hw float32, join Join, miterLimit float32) []float32 {
+	c := dx0*dy1 - dy0*dx1
+	d := dx0*dx1 + dy0*dy1
+
+	// Collinear segments don't need any joint
+	if c == 0 && d > 0 {
+		return nil
+	}
+
+	// Offsets of the outer corners. The outer side is the right
+	// one when the path turns left.
+	ox0, oy0 := -dy0*hw, dx0*hw
+	ox1, oy1 := -dy1*hw, dx1*hw
+	if c > 0 {
+		ox0, oy0, ox1, oy1 = -ox0, -oy0, -ox1, -oy1
+	}
+
+	switch join {
+	case RoundJoin:
+		sweep := math.Atan2(float64(ox0*oy1-oy0*ox1), float64(ox0*ox1+oy0*oy1))
+		return fan(x, y, hw, angleOf(ox0, oy0), sweep)
+	case MiterJoin:
+		mx, my := ox0+ox1, oy0+oy1
+		l := float32(math.Hypot(float64(mx), float64(my)))
+		if l > 0 {
+			mx, my = mx/l, my/l
+			cosHalf := (mx*ox0 + my*oy0) / hw
+			if cosHalf > 0 && 1/cosHalf <= miterLimit {
+				ml := hw / cosHalf
+				return []float32{
+					x + ox0, y + oy0,
+					x, y,
+					x + mx*ml, y + my*ml,
+					x + ox1, y + oy1,
+				}
+			}
+		}
+	}
+
+	// Bevel
+	return []float32{
+		x + ox0, y + oy0,
+		x, y,
+		x + ox1, y + oy1,
+	}
+}
+
+// fan returns a triangle strip approximating the circular sector of
+// radius r centered in (x, y), from angle start sweeping the given
+// angle. Angles are in radians.
+func fan(x, y, r float32, start, sweep float64) []float32 {
+	steps := int(math.Ceil(math.Abs(sweep) / roundStep))
+	if steps < 1 {
+		steps = 1
+	}
+	// A fan is emulated interleaving the center with the points
+	// on the arc.
+	strip := make([]float32, 0, steps*4+2)
+	for i := 0; i <= steps; i++ {
+		a := start + sweep*float64(i)/float64(steps)
+		if i > 0 {
+			strip = append(strip, x, y)
+		}
+		strip = append(strip, x+r*float32(math.Cos(a)), y+r*float32(math.Sin(a)))
+	}
+	return strip
+}
+
+// appendStrip appends the triangle strip src to dst stitching them
+// with degenerate triangles.
+func appendStrip(dst, src []float32) []float32 {
+	if len(src) == 0 {
+		return dst
+	}
+	if n := len(dst); n > 0 {
+		dst = append(dst, dst[n-2], dst[n-1], src[0], src[1])
+	}
+	return append(dst, src...)
+}
+
+// angleOf returns the angle in radians of the vector (x, y).
+func angleOf(x, y float32) float64 {
+	return math.Atan2(float64(y), float64(x))
+}
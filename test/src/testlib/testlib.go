@@ -66,6 +66,10 @@ func (renderState *renderState) init(window mandala.Window) {
 	gl.Viewport(0, 0, gl.Sizei(width), gl.Sizei(height))
 	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
 
+	// Screenshots are taken right after drawing, so wait for the
+	// GPU to complete each draw call.
+	shapes.Synchronous = true
+
 	renderState.boxProgram = shaders.NewProgram(shapes.DefaultBoxFS, shapes.DefaultBoxVS)
 	renderState.segmentProgram = shaders.NewProgram(shapes.DefaultSegmentFS, shapes.DefaultSegmentVS)
 }
@@ -2,10 +2,14 @@ package testlib
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"math"
+	"time"
 
 	"github.com/remogatto/imagetest"
 	"github.com/remogatto/mandala/test/src/testlib"
+	"github.com/remogatto/mathgl"
 	gl "github.com/remogatto/opengles2"
 	"github.com/remogatto/shapes"
 )
@@ -37,8 +41,8 @@ func (t *TestSuite) TestShape() {
 	t.Equal([4]float32{0.6666667, 0.6666667, 0.6666667, 1}, nc)
 
 	// GetSize
-	size := box.Bounds().Size()
-	w, h := size.X, size.Y
+	bounds := box.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
 	t.True(w == 10)
 	t.True(h == 20)
 
@@ -67,9 +71,43 @@ func (t *TestSuite) TestShape() {
 
 	// String representation
 
+	// Bounds enclose the rotated box, so the rotation is undone
+	box.Rotate(0)
 	t.Equal("(5,10)-(15,30)", box.String())
 }
 
+func (t *TestSuite) TestComposedTransform() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 20)
+	box.MoveTo(10, 20)
+	box.Rotate(10)
+
+	// Bounds enclose the rotated box
+
+	t.Equal(image.Rect(3, 9, 17, 31), box.Bounds().Rectangle())
+
+	// Scale doesn't reset the rotation
+
+	box.Scale(2, 2)
+	t.Equal(float32(10), box.Angle())
+	box.RotateBy(-10)
+	t.Equal(float32(0), box.Angle())
+	t.Equal("(0,0)-(20,40)", box.String())
+
+	box.ScaleBy(0.5, 2)
+	sx, sy := box.ScaleFactors()
+	t.Equal(float32(1), sx)
+	t.Equal(float32(4), sy)
+
+	// MoveTo doesn't reset rotation and scale
+
+	box.MoveTo(0, 0)
+	box.SetRotation(90)
+	t.Equal(float32(90), box.Angle())
+	sx, sy = box.ScaleFactors()
+	t.Equal(float32(1), sx)
+	t.Equal(float32(4), sy)
+}
+
 func (t *TestSuite) TestBox() {
 	filename := "expected_box.png"
 	t.rlControl.drawFunc <- func() {
@@ -94,6 +132,42 @@ func (t *TestSuite) TestBox() {
 	}
 }
 
+func (t *TestSuite) TestBoxBuffers() {
+	filename := "expected_box.png"
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+		box := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+		box.AttachToWorld(world)
+		box.MoveTo(float32(w/2), 0)
+
+		// Changing the color uploads it again
+		box.SetColor(color.RGBA{255, 0, 0, 255})
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+		box.SetColor(shapes.DefaultColor)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+
+		// Released buffers are created again
+		box.Release()
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+
+		t.testDraw <- testlib.Screenshot(t.renderState.window)
+		t.renderState.window.SwapBuffers()
+		box.Release()
+	}
+	distance, exp, act, err := testlib.TestImage(filename, <-t.testDraw, imagetest.Center)
+	if err != nil {
+		panic(err)
+	}
+	t.True(distance < distanceThreshold, distanceError(distance, filename))
+	if t.Failed() {
+		saveExpAct(t.outputPath, "failed_"+filename, exp, act)
+	}
+}
+
 func (t *TestSuite) TestRotatedBox() {
 	filename := "expected_box_rotated_20.png"
 	t.rlControl.drawFunc <- func() {
@@ -228,10 +302,56 @@ func (t *TestSuite) TestSegmentCenter() {
 	t.Equal(float32(15), x)
 	t.Equal(float32(17.5), y)
 
-	size := segment.Bounds().Size()
-	w, h := size.X, size.Y
-	t.Equal(10, w)
-	t.Equal(5, h)
+	bounds := segment.Bounds()
+	t.Equal(float32(10), bounds.Dx())
+	t.Equal(float32(5), bounds.Dy())
+
+	// The bounds enclose the stroke
+	segment = shapes.NewSegment(t.renderState.segmentProgram, 0, 0, 100, 0)
+	segment.SetWidth(10)
+	t.Equal(shapes.NewRect(0, -5, 100, 5), segment.Bounds())
+	segment.SetCap(shapes.SquareCap)
+	t.Equal(shapes.NewRect(-5, -5, 105, 5), segment.AABB())
+}
+
+func (t *TestSuite) TestRect() {
+	// Half pixels are not lost
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 81.5, -40, 238.5, 44)
+	t.Equal(shapes.NewRect(81.5, -40, 238.5, 44), segment.Bounds())
+	t.Equal(image.Rect(81, -40, 239, 44), segment.Bounds().Rectangle())
+
+	// Sub-pixel movements don't drift
+	for i := 0; i < 10; i++ {
+		segment.Move(0.25, 0)
+	}
+	t.Equal(shapes.NewRect(84, -40, 241, 44), segment.Bounds())
+
+	r := shapes.NewRect(0, 0, 10, 10)
+	s := shapes.NewRect(20, 15, 5, 5)
+	t.Equal(shapes.NewRect(0, 0, 20, 15), r.Union(s))
+
+	// Degenerate rectangles, like the bounds of a horizontal
+	// segment, are not ignored by the union, unlike the zero one
+	horizontal := shapes.NewSegment(t.renderState.segmentProgram, 0, 30, 40, 30).Bounds()
+	t.Equal(shapes.NewRect(0, 0, 40, 30), r.Union(horizontal))
+	t.Equal(shapes.NewRect(0, 0, 40, 30), horizontal.Union(r))
+	t.Equal(r, r.Union(shapes.Rect{}))
+	t.Equal(shapes.NewRect(5, 5, 10, 10), r.Intersect(s))
+	t.True(r.Intersect(shapes.NewRect(20, 20, 30, 30)).Empty())
+	t.True(r.Contains(10, 5))
+	t.False(r.Contains(10.5, 5))
+	t.Equal(shapes.NewRect(2, 2, 8, 8), r.Inset(2))
+	t.Equal(shapes.NewRect(5, 5, 5, 5), r.Inset(6))
+	t.Equal(shapes.NewRect(0, 0, 10, 10), shapes.FromRectangle(image.Rect(0, 0, 10, 10)))
+
+	// The center of a group is not rounded
+	box := shapes.NewBox(t.renderState.boxProgram, 5, 5)
+	box.MoveTo(1.5, 0.5)
+	group := shapes.NewGroup()
+	group.Append(box)
+	x, y := group.Center()
+	t.Equal(float32(1.5), x)
+	t.Equal(float32(0.5), y)
 }
 
 func (t *TestSuite) TestTexturedBox() {
@@ -389,3 +509,1022 @@ func (t *TestSuite) TestGroup() {
 		saveExpAct(t.outputPath, "failed_"+filename, exp, act)
 	}
 }
+
+func (t *TestSuite) TestBatch() {
+	drawCalls := make(chan int)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+
+		// A hundred boxes sharing the same program
+		group := shapes.NewGroup()
+		for i := 0; i < 100; i++ {
+			box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+			box.MoveTo(float32(i*5), 0)
+			group.Append(box)
+		}
+		group.AttachToWorld(world)
+
+		batch := shapes.NewBatch()
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		batch.Begin()
+		batch.Add(group)
+		batch.End()
+		drawCalls <- batch.DrawCalls()
+
+		// A curve without width is rendered as a line strip and
+		// splits the batch
+		curve := shapes.NewQuadBezier(t.renderState.segmentProgram, 0, 0, 50, 100, 100, 0)
+		curve.AttachToWorld(world)
+		box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+		box.AttachToWorld(world)
+		batch.Begin()
+		batch.Add(group)
+		batch.Add(curve)
+		batch.Add(box)
+		batch.End()
+		drawCalls <- batch.DrawCalls()
+
+		t.renderState.window.SwapBuffers()
+	}
+	t.Equal(1, <-drawCalls)
+	t.Equal(3, <-drawCalls)
+}
+
+func (t *TestSuite) TestBatchView() {
+	centerColor := make(chan color.RGBA, 2)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+
+		// The view matrix is applied before the model matrix: a
+		// box turned upside down and moved to the right of the
+		// center is moved back to the center by a camera
+		// translated to the right
+		world.viewMatrix = mathgl.Translate3D(100, 0, 0)
+		box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+		box.SetColor(color.RGBA{255, 0, 0, 255})
+		box.MoveTo(float32(w/2+100), 0)
+		box.Rotate(180)
+		box.AttachToWorld(world)
+
+		center := func() color.RGBA {
+			img := testlib.Screenshot(t.renderState.window)
+			b := img.Bounds()
+			return color.RGBAModel.Convert(img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2)).(color.RGBA)
+		}
+
+		// Batched and unbatched shapes are rendered the same
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		box.Draw()
+		centerColor <- center()
+
+		batch := shapes.NewBatch()
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		batch.Begin()
+		batch.Add(box)
+		batch.End()
+		centerColor <- center()
+
+		t.renderState.window.SwapBuffers()
+		batch.Release()
+		box.Release()
+	}
+	t.Equal(color.RGBA{255, 0, 0, 255}, <-centerColor)
+	t.Equal(color.RGBA{255, 0, 0, 255}, <-centerColor)
+}
+
+func (t *TestSuite) TestRenderer() {
+	filename := "expected_box.png"
+	drawCalls := make(chan int, 1)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+		renderer := shapes.NewRenderer()
+
+		// Shapes drawn in a frame are enqueued and merged
+		boxes := make([]*shapes.Box, 10)
+		for i := range boxes {
+			boxes[i] = shapes.NewBox(t.renderState.boxProgram, 100, 100)
+			boxes[i].AttachToWorld(world)
+			boxes[i].MoveTo(float32(w/2), 0)
+		}
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		renderer.Begin()
+		for _, box := range boxes {
+			box.Draw()
+		}
+		renderer.End()
+		drawCalls <- renderer.DrawCalls()
+
+		t.testDraw <- testlib.Screenshot(t.renderState.window)
+		t.renderState.window.SwapBuffers()
+		renderer.Release()
+	}
+	distance, exp, act, err := testlib.TestImage(filename, <-t.testDraw, imagetest.Center)
+	if err != nil {
+		panic(err)
+	}
+	t.Equal(1, <-drawCalls)
+	t.True(distance < distanceThreshold, distanceError(distance, filename))
+	if t.Failed() {
+		saveExpAct(t.outputPath, "failed_"+filename, exp, act)
+	}
+}
+
+func (t *TestSuite) TestRendererRepeatedDraw() {
+	colors := make(chan color.RGBA, 3)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+		renderer := shapes.NewRenderer()
+
+		// A shape drawn twice in a frame is rendered in both
+		// places
+		box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+		box.SetColor(color.RGBA{255, 0, 0, 255})
+		box.AttachToWorld(world)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		renderer.Begin()
+		box.MoveTo(float32(w/2-50), 0)
+		box.Draw()
+		box.MoveTo(float32(w/2+50), 0)
+		box.Draw()
+		renderer.End()
+
+		img := testlib.Screenshot(t.renderState.window)
+		b := img.Bounds()
+		at := func(dx int) color.RGBA {
+			return color.RGBAModel.Convert(img.At(b.Min.X+b.Dx()/2+dx, b.Min.Y+b.Dy()/2)).(color.RGBA)
+		}
+		colors <- at(-50)
+		colors <- at(50)
+		colors <- at(0)
+
+		t.renderState.window.SwapBuffers()
+		renderer.Release()
+		box.Release()
+	}
+	t.Equal(color.RGBA{255, 0, 0, 255}, <-colors)
+	t.Equal(color.RGBA{255, 0, 0, 255}, <-colors)
+	t.Equal(color.RGBA{0, 0, 0, 255}, <-colors)
+}
+
+func (t *TestSuite) TestRenderQueue() {
+	drawCalls := make(chan int, 3)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+		renderer := shapes.NewRenderer()
+
+		// Boxes and polylines, using different programs,
+		// alternate in the same layer without overlapping
+		group := shapes.NewGroup()
+		for i := 0; i < 3; i++ {
+			box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+			box.MoveTo(float32(i*40), 0)
+			polyline := shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 0, 10, 10}, 2)
+			polyline.MoveTo(float32(i*40+20), 0)
+			group.Append(box)
+			group.Append(polyline)
+		}
+		group.AttachToWorld(world)
+
+		// Shapes are sorted by program
+		renderer.Begin()
+		group.Draw()
+		renderer.End()
+		drawCalls <- renderer.DrawCalls()
+
+		// A stable sort keeps the drawing order
+		renderer.SetStableSort(true)
+		renderer.Begin()
+		group.Draw()
+		renderer.End()
+		drawCalls <- renderer.DrawCalls()
+
+		// Unless the shapes are in different layers
+		for i := 0; i < 6; i += 2 {
+			group.GetAt(i).SetZ(1)
+		}
+		renderer.Begin()
+		group.Draw()
+		renderer.End()
+		drawCalls <- renderer.DrawCalls()
+
+		renderer.Release()
+		group.Release()
+	}
+	t.Equal(2, <-drawCalls)
+	t.Equal(6, <-drawCalls)
+	t.Equal(2, <-drawCalls)
+}
+
+func (t *TestSuite) TestDrawOrder() {
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	top := make(chan color.RGBA, 4)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+
+		// Overlapping boxes and polyline, using different
+		// programs, in the same layer
+		back := shapes.NewBox(t.renderState.boxProgram, 100, 100)
+		back.SetColor(red)
+		line := shapes.NewPolyline(t.renderState.segmentProgram, []float32{-50, 0, 50, 0}, 20)
+		line.SetColor(green)
+		front := shapes.NewBox(t.renderState.boxProgram, 60, 60)
+		front.SetColor(blue)
+		group := shapes.NewGroup()
+		group.Append(back)
+		group.Append(line)
+		group.Append(front)
+		group.AttachToWorld(world)
+		group.MoveTo(float32(w/2), 0)
+
+		// topColor returns the color drawn at the center of the
+		// window by the group
+		renderer := shapes.NewRenderer()
+		topColor := func(inFrame bool) color.RGBA {
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			if inFrame {
+				renderer.Begin()
+				group.Draw()
+				renderer.End()
+			} else {
+				group.Draw()
+			}
+			img := testlib.Screenshot(t.renderState.window)
+			b := img.Bounds()
+			return color.RGBAModel.Convert(img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2)).(color.RGBA)
+		}
+		top <- topColor(false)
+		group.BringToFront(back)
+		top <- topColor(false)
+		group.BringToFront(line)
+		top <- topColor(false)
+		group.SendToBack(line)
+		top <- topColor(true)
+
+		t.renderState.window.SwapBuffers()
+		renderer.Release()
+		group.Release()
+	}
+	t.Equal(blue, <-top)
+	t.Equal(red, <-top)
+	t.Equal(green, <-top)
+	t.Equal(red, <-top)
+}
+
+func (t *TestSuite) TestLayers() {
+	a := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	b := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	c := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	group := shapes.NewGroup()
+	group.Append(a)
+	group.Append(b)
+	group.Append(c)
+
+	group.BringToFront(a)
+	t.True(group.GetAt(0) == b)
+	t.True(group.GetAt(1) == c)
+	t.True(group.GetAt(2) == a)
+
+	group.SendToBack(c)
+	t.True(group.GetAt(0) == c)
+	t.True(group.GetAt(1) == b)
+	t.True(group.GetAt(2) == a)
+
+	// Shapes not in the group are ignored
+	group.BringToFront(shapes.NewBox(t.renderState.boxProgram, 10, 10))
+	t.True(group.GetAt(2) == a)
+
+	a.SetZ(2)
+	t.Equal(float32(2), a.Z())
+}
+
+func (t *TestSuite) TestPivot() {
+	// A door hinged on its left side
+	door := shapes.NewBox(t.renderState.boxProgram, 100, 20)
+	door.SetAnchor(0, 0.5)
+
+	px, py := door.Pivot()
+	t.Equal(float32(-50), px)
+	t.Equal(float32(0), py)
+
+	// The door stays in place
+	x, y := door.Center()
+	t.Equal(float32(-50), x)
+	t.Equal(float32(0), y)
+	t.Equal("(-50,-10)-(50,10)", door.String())
+
+	// MoveTo places the hinge
+	door.MoveTo(0, 0)
+	t.Equal("(0,-10)-(100,10)", door.String())
+
+	// Rotation and scale are applied around the hinge
+	door.Rotate(90)
+	t.Equal("(-10,0)-(10,100)", door.String())
+	door.Scale(0.5, 1)
+	t.Equal("(-10,0)-(10,50)", door.String())
+}
+
+func (t *TestSuite) TestSkewAndTransform() {
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+
+	// Bounds follow the skewed vertices
+	box.Skew(45, 0)
+	t.Equal("(-20,-10)-(20,10)", box.String())
+
+	// A matrix computed elsewhere is decomposed
+	other := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	other.SetTransform(mathgl.Translate3D(100, 50, 0).Mul4(mathgl.HomogRotate3DZ(90)))
+	x, y := other.Center()
+	t.Equal(float32(100), x)
+	t.Equal(float32(50), y)
+	t.Equal(float32(90), other.Angle())
+	t.Equal("(90,40)-(110,60)", other.String())
+
+	// Setting the matrix of a shape to the one of another one
+	// gives the same transform
+	box.MoveTo(10, 5)
+	box.Rotate(30)
+	box.Scale(2, 3)
+	other.SetTransform(box.Transform())
+	sx, sy := other.ScaleFactors()
+	kx, _ := other.SkewAngles()
+	t.True(math.Abs(float64(sx-2)) < 1e-4)
+	t.True(math.Abs(float64(sy-3)) < 1e-4)
+	t.True(math.Abs(float64(kx-45)) < 1e-4)
+	t.True(math.Abs(float64(other.Angle()-30)) < 1e-4)
+}
+
+func (t *TestSuite) TestNestedGroup() {
+	b1 := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b1.MoveTo(30, 40)
+	b2 := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b2.MoveTo(70, 40)
+
+	inner := shapes.NewGroup()
+	inner.Append(b1)
+	inner.Append(b2)
+	outer := shapes.NewGroup()
+	outer.Append(inner)
+
+	x, y := inner.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(40), y)
+	t.Equal("(20,30)-(80,50)", outer.Bounds().String())
+
+	// Children are not modified by the transform of the group
+
+	inner.Rotate(90)
+	t.Equal("(40,10)-(60,70)", outer.Bounds().String())
+	t.Equal("(20,30)-(40,50)", b1.Bounds().String())
+
+	outer.Move(100, 0)
+	outer.Scale(2, 2)
+	t.Equal("(130,-20)-(170,100)", outer.Bounds().String())
+	t.Equal(float32(0), b1.Angle())
+}
+
+func (t *TestSuite) TestGroupClone() {
+	group := shapes.NewGroup()
+	group.Append(shapes.NewBox(t.renderState.boxProgram, 10, 10))
+	group.Append(shapes.NewBox(t.renderState.boxProgram, 20, 40))
+	group.Move(100, 50)
+	group.Rotate(30)
+	group.Scale(2, 1)
+	group.SetZ(3)
+
+	// The clone is placed as the group
+	clone := group.Clone()
+	t.Equal(group.WorldVertices(), clone.WorldVertices())
+	t.Equal(group.AABB(), clone.AABB())
+	t.Equal(float32(3), clone.Z())
+}
+
+func (t *TestSuite) TestWorldBounds() {
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 20)
+	box.MoveTo(10, 20)
+	box.Rotate(30)
+
+	// The oriented box follows the rotation
+	obb := box.OBB()
+	t.True(math.Abs(float64(obb.X-10)) < 1e-4)
+	t.True(math.Abs(float64(obb.Y-20)) < 1e-4)
+	t.True(math.Abs(float64(obb.HalfWidth-5)) < 1e-4)
+	t.True(math.Abs(float64(obb.HalfHeight-10)) < 1e-4)
+	t.True(math.Abs(float64(obb.Angle-30)) < 1e-4)
+	t.Equal(box.Bounds(), box.AABB())
+	t.Equal(len(box.Vertices()), len(box.WorldVertices()))
+
+	// Children of a group are transformed by the group
+	b1 := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b1.MoveTo(30, 40)
+	b2 := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b2.MoveTo(70, 40)
+	inner := shapes.NewGroup()
+	inner.Append(b1)
+	inner.Append(b2)
+	outer := shapes.NewGroup()
+	outer.Append(inner)
+
+	inner.Rotate(90)
+	t.Equal("(20,30)-(40,50)", b1.Bounds().String())
+	t.Equal("(40,10)-(60,30)", b1.AABB().String())
+
+	outer.Move(100, 0)
+	outer.Scale(2, 2)
+	t.Equal("(130,-20)-(170,20)", b1.AABB().String())
+	t.Equal(outer.AABB(), outer.Bounds())
+	t.Equal("(130,-20)-(170,100)", inner.AABB().String())
+}
+
+func (t *TestSuite) TestContains() {
+	// Rotation is taken into account
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.MoveTo(100, 100)
+	t.True(box.Contains(108, 108))
+	t.False(box.Contains(112, 100))
+	box.Rotate(45)
+	t.False(box.Contains(108, 108))
+	t.True(box.Contains(112, 100))
+
+	circle := shapes.NewCircle(t.renderState.boxProgram, 10, 32)
+	t.True(circle.Contains(7, 7))
+	t.False(circle.Contains(8, 8))
+
+	// A concave polygon
+	polygon := shapes.NewPolygon(t.renderState.boxProgram, []float32{
+		0, 0, 20, 0, 20, 10, 10, 10, 10, 20, 0, 20,
+	})
+	t.True(polygon.Contains(5, 15))
+	t.False(polygon.Contains(15, 15))
+
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 0, 0, 100, 0)
+	t.True(segment.Contains(50, 1))
+	t.False(segment.Contains(50, 5))
+	segment.SetWidth(10)
+	t.True(segment.Contains(50, 4))
+
+	// Picking returns the top-most shapes first
+	bottom := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	top := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	top.MoveTo(10, 0)
+	inner := shapes.NewGroup()
+	inner.Append(top)
+	group := shapes.NewGroup()
+	group.Append(bottom)
+	group.Append(inner)
+
+	picked := group.PickAt(5, 0)
+	t.Equal(2, len(picked))
+	if len(picked) == 2 {
+		t.True(picked[0] == top)
+		t.True(picked[1] == bottom)
+	}
+	picked = group.PickAt(-5, 0)
+	t.Equal(1, len(picked))
+
+	// Unless the bottom one is in a higher layer
+	bottom.SetZ(1)
+	picked = group.PickAt(5, 0)
+	t.Equal(2, len(picked))
+	if len(picked) == 2 {
+		t.True(picked[0] == bottom)
+		t.True(picked[1] == top)
+	}
+	inner.SetZ(2)
+	picked = group.PickAt(5, 0)
+	t.Equal(2, len(picked))
+	if len(picked) == 2 {
+		t.True(picked[0] == top)
+	}
+	t.Equal(0, len(group.PickAt(50, 50)))
+
+	// The transform of the group is taken into account
+	group.Move(100, 0)
+	t.False(group.Contains(5, 0))
+	t.True(group.Contains(105, 0))
+}
+
+func (t *TestSuite) TestCollision() {
+	a := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	b.MoveTo(15, 5)
+
+	// The manifold pushes b out of a along the shortest way
+	m, ok := shapes.Collide(a, b)
+	t.True(ok)
+	t.Equal([2]float32{1, 0}, m.Normal)
+	t.Equal(float32(5), m.Depth)
+
+	b.MoveTo(25, 0)
+	t.False(shapes.Intersects(a, b))
+
+	// Rotation is taken into account
+	a.Rotate(45)
+	b.MoveTo(24, 0)
+	t.True(shapes.Intersects(a, b))
+	b.MoveTo(25, 0)
+	t.False(shapes.Intersects(a, b))
+
+	c1 := shapes.NewCircle(t.renderState.boxProgram, 10, 32)
+	c2 := shapes.NewCircle(t.renderState.boxProgram, 10, 32)
+	c2.MoveTo(15, 0)
+	m, ok = shapes.Collide(c1, c2)
+	t.True(ok)
+	t.Equal([2]float32{1, 0}, m.Normal)
+	t.Equal(float32(5), m.Depth)
+
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.MoveTo(18, 0)
+	m, ok = shapes.Collide(c1, box)
+	t.True(ok)
+	t.Equal([2]float32{1, 0}, m.Normal)
+	t.Equal(float32(2), m.Depth)
+
+	// Strokes are split in triangles
+	l1 := shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 0, 100, 100}, 2)
+	l2 := shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 100, 100, 0}, 2)
+	t.True(shapes.Intersects(l1, l2))
+	l1 = shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 0, 50, 0, 100, 0}, 2)
+	l2 = shapes.NewPolyline(t.renderState.segmentProgram, []float32{0, 10, 50, 10, 100, 10}, 2)
+	t.False(shapes.Intersects(l1, l2))
+
+	// A concave polygon doesn't collide with shapes in its notch
+	polygon := shapes.NewPolygon(t.renderState.boxProgram, []float32{
+		0, 0, 20, 0, 20, 10, 10, 10, 10, 20, 0, 20,
+	})
+	box = shapes.NewBox(t.renderState.boxProgram, 4, 4)
+	box.MoveTo(15, 15)
+	t.False(shapes.Intersects(polygon, box))
+	box.MoveTo(5, 15)
+	t.True(shapes.Intersects(polygon, box))
+
+	// Groups collide through their children
+	group := shapes.NewGroup()
+	group.Append(box)
+	group.Move(100, 0)
+	t.False(shapes.Intersects(polygon, group))
+	l1 = shapes.NewPolyline(t.renderState.segmentProgram, []float32{100, 0, 110, 30}, 2)
+	t.True(shapes.Intersects(group, l1))
+}
+
+func (t *TestSuite) TestRaycast() {
+	box := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	box.MoveTo(100, 0)
+
+	hit, ok := box.Raycast(0, 0, 1, 0, 1000)
+	t.True(ok)
+	t.True(hit.Shape == box)
+	t.Equal(float32(90), hit.X)
+	t.Equal(float32(0), hit.Y)
+	t.Equal([2]float32{-1, 0}, hit.Normal)
+	t.Equal(float32(90), hit.Distance)
+
+	// Hits farther than the maximum distance or behind the origin
+	// are ignored
+	_, ok = box.Raycast(0, 0, 1, 0, 50)
+	t.False(ok)
+	_, ok = box.Raycast(0, 0, -1, 0, 1000)
+	t.False(ok)
+
+	// The origin inside the shape is hit at distance zero
+	hit, ok = box.Raycast(100, 0, 1, 0, 1000)
+	t.True(ok)
+	t.Equal(float32(0), hit.Distance)
+
+	// The direction doesn't need to be normalized
+	circle := shapes.NewCircle(t.renderState.boxProgram, 10, 32)
+	circle.MoveTo(0, 100)
+	hit, ok = circle.Raycast(0, 0, 0, 2, 1000)
+	t.True(ok)
+	t.Equal([2]float32{0, -1}, hit.Normal)
+	t.Equal(float32(90), hit.Distance)
+
+	// Groups report the nearest child
+	near := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	near.MoveTo(50, 0)
+	group := shapes.NewGroup()
+	group.Append(box)
+	group.Append(near)
+	group.Move(0, 50)
+	_, ok = group.Raycast(0, 0, 1, 0, 1000)
+	t.False(ok)
+	hit, ok = group.Raycast(0, 50, 1, 0, 1000)
+	t.True(ok)
+	t.True(hit.Shape == near)
+	t.Equal(float32(40), hit.Distance)
+
+	// On ties the top-most shape wins
+	over := shapes.NewBox(t.renderState.boxProgram, 20, 20)
+	over.MoveTo(50, 0)
+	group.Append(over)
+	hit, _ = group.Raycast(0, 50, 1, 0, 1000)
+	t.True(hit.Shape == over)
+	near.SetZ(1)
+	hit, _ = group.Raycast(0, 50, 1, 0, 1000)
+	t.True(hit.Shape == near)
+
+	// Segments are hit on their stroke
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 100, -50, 100, 50)
+	hit, ok = segment.Raycast(0, 0, 1, 0, 1000)
+	t.True(ok)
+	t.Equal(float32(100), hit.Distance)
+	segment.SetWidth(10)
+	hit, _ = segment.Raycast(0, 0, 1, 0, 1000)
+	t.Equal(float32(95), hit.Distance)
+	hit, _ = segment.Clone().Raycast(0, 0, 1, 0, 1000)
+	t.Equal(float32(95), hit.Distance)
+}
+
+func (t *TestSuite) TestSpatialIndex() {
+	a := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	b := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	b.MoveTo(100, 0)
+	c := shapes.NewCircle(t.renderState.boxProgram, 5, 32)
+	c.MoveTo(1000, 1000)
+
+	index := shapes.NewSpatialIndex(50)
+	index.Insert(a)
+	index.Insert(b)
+	index.Insert(c)
+	t.Equal(3, index.Len())
+
+	found := index.Query(shapes.NewRect(-10, -10, 110, 10))
+	t.Equal(2, len(found))
+	if len(found) == 2 {
+		t.True(found[0] == a)
+		t.True(found[1] == b)
+	}
+	found = index.QueryPoint(100, 0)
+	t.Equal(1, len(found))
+	if len(found) == 1 {
+		t.True(found[0] == b)
+	}
+	t.Equal(0, len(index.QueryPoint(50, 0)))
+
+	// The circle contains its bounds' center but not their corners
+	t.Equal(0, len(index.QueryPoint(1004.5, 1004.5)))
+
+	nearest, ok := index.Nearest(900, 900)
+	t.True(ok)
+	t.True(nearest == c)
+	nearest, _ = index.Nearest(40, 0)
+	t.True(nearest == a)
+
+	// Points far from the indexed shapes are answered quickly
+	nearest, _ = index.Nearest(-1e6, -1e6)
+	t.True(nearest == a)
+	nearest, _ = index.Nearest(1e6, 1e6)
+	t.True(nearest == c)
+
+	// Even after a shape went far away and came back
+	a.MoveTo(1e7, 0)
+	a.MoveTo(0, 0)
+	nearest, _ = index.Nearest(5e6, 0)
+	t.True(nearest == c)
+
+	// Moving a shape updates the index
+	b.MoveTo(500, 500)
+	t.Equal(1, len(index.Query(shapes.NewRect(-10, -10, 110, 10))))
+	t.Equal(1, len(index.QueryPoint(500, 500)))
+
+	// So does moving the group containing it
+	group := shapes.NewGroup()
+	group.Append(a)
+	group.Move(300, 0)
+	t.Equal(0, len(index.QueryPoint(0, 0)))
+	found = index.QueryPoint(300, 0)
+	t.Equal(1, len(found))
+	if len(found) == 1 {
+		t.True(found[0] == a)
+	}
+
+	index.Remove(a)
+	t.Equal(2, index.Len())
+	t.Equal(0, len(index.QueryPoint(300, 0)))
+	a.Move(200, 500)
+	t.Equal(1, len(index.QueryPoint(500, 500)))
+
+	// Widening a segment updates the index
+	segment := shapes.NewSegment(t.renderState.segmentProgram, 1000, -50, 1000, 50)
+	index.Insert(segment)
+	t.Equal(0, len(index.QueryPoint(997, 0)))
+	segment.SetWidth(10)
+	t.Equal(1, len(index.QueryPoint(997, 0)))
+
+	_, ok = shapes.NewSpatialIndex(50).Nearest(0, 0)
+	t.False(ok)
+}
+
+func (t *TestSuite) TestCircle() {
+	circle := shapes.NewCircle(t.renderState.boxProgram, 50, 32)
+
+	x, y := circle.Center()
+	t.Equal(float32(0), x)
+	t.Equal(float32(0), y)
+
+	bounds := circle.Bounds()
+	t.Equal(float32(100), bounds.Dx())
+	t.Equal(float32(100), bounds.Dy())
+
+	// The triangle fan is made of the center plus segments+1
+	// points on the circumference
+	t.Equal(2*34, len(circle.Vertices()))
+}
+
+func (t *TestSuite) TestPolygon() {
+	// A concave L-shaped outline
+	polygon := shapes.NewPolygon(t.renderState.boxProgram, []float32{
+		0, 0,
+		40, 0,
+		40, 20,
+		20, 20,
+		20, 40,
+		0, 40,
+	})
+
+	x, y := polygon.Center()
+	t.Equal(float32(20), x)
+	t.Equal(float32(20), y)
+
+	t.Equal("(0,0)-(40,40)", polygon.String())
+
+	// Six vertices are split in four triangles
+	t.Equal(4*3*2, len(polygon.Vertices()))
+	t.Equal(6*2, len(polygon.Outline()))
+
+	// A trailing coordinate without its pair is ignored
+	polygon = shapes.NewPolygon(t.renderState.boxProgram, []float32{0, 0, 40, 0, 0, 40, 100})
+	t.Equal("(0,0)-(40,40)", polygon.String())
+	t.Equal(3*2, len(polygon.Outline()))
+}
+
+func (t *TestSuite) TestPolyline() {
+	polyline := shapes.NewPolyline(t.renderState.segmentProgram, []float32{
+		0, 0,
+		100, 0,
+	}, 10)
+
+	x, y := polyline.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(0), y)
+
+	// Butt caps don't extend the stroke
+	t.Equal("(0,-5)-(100,5)", polyline.String())
+
+	// Square caps extend the stroke by half of its width
+	polyline.SetCap(shapes.SquareCap)
+	t.Equal("(-5,-5)-(105,5)", polyline.String())
+}
+
+func (t *TestSuite) TestPolylineVertexColors() {
+	red := color.RGBA{255, 0, 0, 255}
+	centerColor := make(chan color.RGBA, 2)
+	t.rlControl.drawFunc <- func() {
+		w, h := t.renderState.window.GetSize()
+		world := newWorld(w, h)
+
+		// Vertex colors survive changing the stroke style and
+		// cloning
+		polyline := shapes.NewPolyline(t.renderState.segmentProgram, []float32{
+			float32(w/2 - 50), 0,
+			float32(w/2 + 50), 0,
+		}, 20)
+		polyline.SetVertexColors([]color.Color{red, red, red, red})
+		polyline.SetCap(shapes.SquareCap)
+		clone := polyline.Clone()
+		polyline.AttachToWorld(world)
+		clone.AttachToWorld(world)
+
+		center := func(s shapes.Shape) color.RGBA {
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			s.Draw()
+			img := testlib.Screenshot(t.renderState.window)
+			b := img.Bounds()
+			return color.RGBAModel.Convert(img.At(b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2)).(color.RGBA)
+		}
+		centerColor <- center(polyline)
+		centerColor <- center(clone)
+
+		t.renderState.window.SwapBuffers()
+		polyline.Release()
+		clone.Release()
+	}
+	t.Equal(red, <-centerColor)
+	t.Equal(red, <-centerColor)
+}
+
+func (t *TestSuite) TestRoundedBox() {
+	box := shapes.NewRoundedBox(t.renderState.boxProgram, 100, 50, 10, 4)
+
+	// Bounds are the same of a box with the same size
+	t.Equal(shapes.NewBox(t.renderState.boxProgram, 100, 50).String(), box.String())
+
+	// Center, four corners of five points each and the closing
+	// point of the fan
+	t.Equal((1+4*5+1)*2, len(box.Vertices()))
+
+	// The radius can't exceed half of the shortest side
+	box = shapes.NewRoundedBox(t.renderState.boxProgram, 100, 50, 40, 4)
+	t.Equal(float32(25), box.Radius())
+}
+
+func (t *TestSuite) TestPie() {
+	pie := shapes.NewPie(t.renderState.segmentProgram, 50, 0, 90, 16)
+	t.Equal("(0,0)-(50,50)", pie.String())
+
+	// Changing the angles doesn't change the number of vertices
+	n := len(pie.Vertices())
+	pie.SetAngles(0, 180)
+	t.Equal(n, len(pie.Vertices()))
+	t.Equal("(-50,0)-(50,50)", pie.String())
+}
+
+func (t *TestSuite) TestRegularPolygon() {
+	hexagon := shapes.NewRegularPolygon(t.renderState.boxProgram, 6, 50)
+
+	x, y := hexagon.Center()
+	t.Equal(float32(0), x)
+	t.Equal(float32(0), y)
+	t.Equal(6*2, len(hexagon.Outline()))
+	t.Equal(shapes.DefaultColor, hexagon.Color())
+
+	star := shapes.NewStar(t.renderState.boxProgram, 5, 20, 50)
+	t.Equal(10*2, len(star.Outline()))
+
+	// The star is split in eight triangles
+	t.Equal(8*3*2, len(star.Vertices()))
+}
+
+func (t *TestSuite) TestBezier() {
+	bezier := shapes.NewQuadBezier(t.renderState.segmentProgram, 0, 0, 50, 100, 100, 0)
+
+	// The flattened curve starts and ends at the end points
+	v := bezier.Vertices()
+	t.True(len(v) > 4)
+	t.Equal([]float32{0, 0}, v[:2])
+	t.Equal([]float32{100, 0}, v[len(v)-2:])
+
+	// A lower tolerance gives more points
+	n := len(v)
+	bezier.SetTolerance(0.1)
+	t.True(len(bezier.Vertices()) > n)
+
+	// Tolerances that are not positive are replaced by the default
+	bezier.SetTolerance(0)
+	t.Equal(shapes.DefaultCurveTolerance, bezier.Tolerance())
+	t.Equal(n, len(bezier.Vertices()))
+	bezier.SetTolerance(float32(math.NaN()))
+	t.Equal(shapes.DefaultCurveTolerance, bezier.Tolerance())
+
+	// Setting the control points flattens the curve again
+	bezier.SetControlPoints(0, 0, 50, 0, 100, 0)
+	t.Equal([]float32{0, 0, 100, 0}, bezier.Vertices())
+}
+
+func (t *TestSuite) TestPath() {
+	// A 100x100 square with a 50x50 square hole
+	p := shapes.NewPath()
+	p.MoveTo(0, 0)
+	p.LineTo(100, 0)
+	p.LineTo(100, 100)
+	p.LineTo(0, 100)
+	p.Close()
+	p.MoveTo(25, 25)
+	p.LineTo(75, 25)
+	p.LineTo(75, 75)
+	p.LineTo(25, 75)
+	p.Close()
+
+	filled := p.Fill(t.renderState.boxProgram, shapes.EvenOdd)
+	x, y := filled.Center()
+	t.Equal(float32(50), x)
+	t.Equal(float32(50), y)
+	t.Equal("(0,0)-(100,100)", filled.String())
+
+	// The hole splits the square in three slabs, the middle one
+	// made of two trapezoids of two triangles each
+	t.Equal(4*2*3*2, len(filled.Vertices()))
+
+	// With the nonzero rule the equally oriented inner square is
+	// filled too
+	filled = p.Fill(t.renderState.boxProgram, shapes.NonZero)
+	t.Equal(3*2*3*2, len(filled.Vertices()))
+
+	stroked := p.Stroke(t.renderState.segmentProgram, 10, shapes.MiterJoin, shapes.ButtCap)
+	t.Equal("(-5,-5)-(105,105)", stroked.String())
+
+	// Tolerances that are not positive are replaced by the default
+	curved := func(tolerance float32) int {
+		p := shapes.NewPath()
+		p.SetTolerance(tolerance)
+		p.MoveTo(0, 0)
+		p.QuadTo(50, 100, 100, 0)
+		return len(p.Stroke(t.renderState.segmentProgram, 2, shapes.MiterJoin, shapes.ButtCap).Vertices())
+	}
+	t.Equal(curved(shapes.DefaultCurveTolerance), curved(0))
+	t.Equal(curved(shapes.DefaultCurveTolerance), curved(float32(math.NaN())))
+}
+
+func (t *TestSuite) TestMesh() {
+	red := color.RGBA{255, 0, 0, 255}
+	green := color.RGBA{0, 255, 0, 255}
+
+	// A quad made of two indexed triangles with a gradient
+	mesh, err := shapes.NewMesh(
+		t.renderState.boxProgram,
+		[]float32{0, 0, 100, 0, 0, 50, 100, 50},
+		[]uint16{0, 1, 2, 2, 1, 3},
+		[]color.Color{red, green, red, green},
+		nil,
+	)
+	t.True(err == nil)
+	t.Equal("(0,0)-(100,50)", mesh.String())
+
+	// Indices must reference existing vertices
+	_, err = shapes.NewMesh(t.renderState.boxProgram, []float32{0, 0, 1, 0, 0, 1}, []uint16{0, 1, 3}, nil, nil)
+	t.True(err != nil)
+
+	// A box has four vertices
+	box := shapes.NewBox(t.renderState.boxProgram, 10, 10)
+	t.True(box.SetVertexColors([]color.Color{red, green, red, green}) == nil)
+	t.True(box.SetVertexColors([]color.Color{red}) != nil)
+
+	triangle := shapes.NewTriangle(t.renderState.boxProgram, 0, 0, 10, 0, 0, 10)
+	t.Equal(6, len(triangle.Vertices()))
+}
+
+func (t *TestSuite) TestSprite() {
+	sprite := shapes.NewSprite(t.renderState.boxProgram, shapes.SpriteSheet{
+		FrameWidth:  32,
+		FrameHeight: 48,
+		Rows:        2,
+		Cols:        4,
+	})
+
+	// The sprite is as big as a frame
+	bounds := sprite.Bounds()
+	t.Equal(float32(32), bounds.Dx())
+	t.Equal(float32(48), bounds.Dy())
+	t.Equal(8, sprite.FrameCount())
+
+	sprite.SetFrame(9)
+	t.Equal(1, sprite.Frame())
+
+	sprite.AddAnimation("walk", shapes.Animation{Frames: []int{4, 5, 6}, FPS: 10, Loop: true})
+	t.True(sprite.Play("run") != nil)
+	t.True(sprite.Play("walk") == nil)
+	t.Equal(4, sprite.Frame())
+
+	sprite.Update(150 * time.Millisecond)
+	t.Equal(5, sprite.Frame())
+	sprite.Update(200 * time.Millisecond)
+	t.Equal(4, sprite.Frame())
+
+	// Setting the texture replaces the sheet, leaving the texture
+	// coordinates of the caller untouched
+	texCoords := []float32{0, 0, 1, 0, 0, 1, 1, 1}
+	t.True(sprite.SetTexture(7, texCoords) == nil)
+	t.Equal(uint32(7), sprite.Sheet().Texture)
+	sprite.SetFrame(3)
+	t.Equal([]float32{0, 0, 1, 0, 0, 1, 1, 1}, texCoords)
+}
+
+func (t *TestSuite) TestNinePatch() {
+	// A 10x10 nine-patch image: the stretchable region of the
+	// 8x8 content goes from pixel 2 to pixel 5 on both axes.
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for i := 3; i <= 6; i++ {
+		img.Set(i, 0, color.Black)
+		img.Set(0, i, color.Black)
+	}
+	content, insets, err := shapes.ParseNinePatch(img)
+	t.True(err == nil)
+	t.Equal(shapes.Insets{Left: 2, Top: 2, Right: 2, Bottom: 2}, insets)
+	t.Equal(image.Rect(0, 0, 8, 8), content.Bounds())
+
+	_, _, err = shapes.ParseNinePatch(image.NewNRGBA(image.Rect(0, 0, 10, 10)))
+	t.True(err != nil)
+
+	patch := shapes.NewNinePatch(t.renderState.boxProgram, 0, 8, 8, insets)
+	patch.SetSize(100, 50)
+	t.Equal("(-50,-25)-(50,25)", patch.String())
+
+	// Corners keep their size
+	v := patch.Vertices()
+	t.Equal([]float32{-50, -25, -48, -25}, v[:4])
+
+	// Scale transforms the box as a whole, corners included,
+	// without changing its size
+	patch.Scale(2, 2)
+	w, h := patch.Size()
+	t.Equal(float32(100), w)
+	t.Equal(float32(50), h)
+	t.Equal(shapes.NewRect(-100, -50, 100, 50), patch.AABB())
+}
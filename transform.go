@@ -0,0 +1,394 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/remogatto/mathgl"
+)
+
+// transform holds the position, the rotation and the scale of a
+// shape or a group and composes them in a model matrix.
+type transform struct {
+	// Position of the pivot, that is the center of the shape
+	// unless a different pivot is set
+	x, y float32
+
+	// Angle in degrees
+	angle float32
+
+	// Scale factors
+	sx, sy float32
+
+	// Skew angles in degrees along the x and y axes
+	kx, ky float32
+
+	// Point of the local space placed at (x, y), around which
+	// rotation and scale are applied
+	px, py float32
+
+	// Whether the pivot was set with SetPivot
+	customPivot bool
+
+	// Layer of the shape, relative to the group containing it
+	z float32
+
+	// Whether the matrix must be composed again
+	dirty bool
+
+	// Model matrix composed from the fields above
+	matrix mathgl.Mat4f
+
+	// Transform of the group containing the shape
+	parent *transform
+
+	// Transforms of the shapes contained in the group
+	children []*transform
+
+	// Observers notified when the bounds of the shape in world
+	// coordinates may have changed
+	observers []boundsObserver
+}
+
+// boundsObserver is notified when the bounds of a shape in world
+// coordinates may have changed.
+type boundsObserver interface {
+	boundsChanged()
+}
+
+// initTransform resets the transform to no rotation and unit scale,
+// leaving the shape at its current position.
+func (t *transform) initTransform() {
+	t.angle = 0
+	t.sx, t.sy = 1, 1
+	t.kx, t.ky = 0, 0
+	t.changed()
+}
+
+// updateMatrix composes the translation, the rotation, the skew and
+// the scale in the model matrix, if any of them changed since the
+// last call. The pivot is moved to the position of the shape and the
+// other transformations are applied around it.
+func (t *transform) updateMatrix() {
+	if !t.dirty {
+		return
+	}
+	t.matrix = mathgl.Translate3D(t.x, t.y, 0).
+		Mul4(mathgl.HomogRotate3DZ(t.angle)).
+		Mul4(skew3D(t.kx, t.ky)).
+		Mul4(mathgl.Scale3D(t.sx, t.sy, 1)).
+		Mul4(mathgl.Translate3D(-t.px, -t.py, 0))
+	t.dirty = false
+}
+
+// skew3D returns a matrix skewing by the given angles in degrees
+// along the x and y axes.
+func skew3D(ax, ay float32) mathgl.Mat4f {
+	m := mathgl.Ident4f()
+	m[4] = float32(math.Tan(radians(ax)))
+	m[1] = float32(math.Tan(radians(ay)))
+	return m
+}
+
+// worldMatrix returns the model matrix combined with the ones of
+// the groups containing the shape.
+func (t *transform) worldMatrix() mathgl.Mat4f {
+	t.updateMatrix()
+	if t.parent == nil {
+		return t.matrix
+	}
+	return t.parent.worldMatrix().Mul4(t.matrix)
+}
+
+// parentMatrix returns the matrix transforming the coordinates of
+// the group containing the shape in world coordinates.
+func (t *transform) parentMatrix() mathgl.Mat4f {
+	if t.parent == nil {
+		return mathgl.Ident4f()
+	}
+	return t.parent.worldMatrix()
+}
+
+// setParent sets the transform of the group containing the shape.
+func (t *transform) setParent(parent *transform) {
+	if t.parent != nil {
+		siblings := t.parent.children
+		for i, c := range siblings {
+			if c == t {
+				t.parent.children = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	t.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, t)
+	}
+	t.changed()
+}
+
+// addObserver registers o to be notified when the bounds of the
+// shape in world coordinates may have changed.
+func (t *transform) addObserver(o boundsObserver) {
+	t.observers = append(t.observers, o)
+}
+
+// removeObserver unregisters o.
+func (t *transform) removeObserver(o boundsObserver) {
+	for i, obs := range t.observers {
+		if obs == o {
+			t.observers = append(t.observers[:i], t.observers[i+1:]...)
+			return
+		}
+	}
+}
+
+// changed marks the matrix to be composed again and notifies the
+// observers of the shape, of the shapes it contains, whose world
+// coordinates change too, and of the groups containing it, whose
+// bounds change.
+func (t *transform) changed() {
+	t.dirty = true
+	t.notifySubtree()
+	for p := t.parent; p != nil; p = p.parent {
+		p.notify()
+	}
+}
+
+// boundsChanged notifies the observers of the shape and of the groups
+// containing it that the shape was resized.
+func (t *transform) boundsChanged() {
+	for p := t; p != nil; p = p.parent {
+		p.notify()
+	}
+}
+
+// notifySubtree notifies the observers of the shape and of the shapes
+// it contains.
+func (t *transform) notifySubtree() {
+	t.notify()
+	for _, c := range t.children {
+		c.notifySubtree()
+	}
+}
+
+// notify notifies the observers of the shape.
+func (t *transform) notify() {
+	for _, o := range t.observers {
+		o.boundsChanged()
+	}
+}
+
+// copyTransform copies the position, rotation, scale, skew, pivot
+// and layer of src. The group containing the shape, its children and
+// its observers are left untouched.
+func (t *transform) copyTransform(src *transform) {
+	t.x, t.y = src.x, src.y
+	t.angle = src.angle
+	t.sx, t.sy = src.sx, src.sy
+	t.kx, t.ky = src.kx, src.ky
+	t.px, t.py = src.px, src.py
+	t.customPivot = src.customPivot
+	t.z = src.z
+	t.changed()
+}
+
+// movePivot moves the pivot to (px, py) in local coordinates. The
+// center is moved accordingly, so that the shape stays in place.
+func (t *transform) movePivot(px, py float32) {
+	t.updateMatrix()
+	t.x, t.y = transformPoint(t.matrix, px, py)
+	t.px, t.py = px, py
+	t.dirty = true
+}
+
+// centerPivot moves the pivot to the center of the shape, given in
+// local coordinates, unless a pivot was set with SetPivot.
+func (t *transform) centerPivot(cx, cy float32) {
+	if !t.customPivot {
+		t.movePivot(cx, cy)
+	}
+}
+
+// SetPivot sets the point, in local coordinates, around which the
+// shape rotates and scales and which is placed at the position given
+// to MoveTo. The shape stays in place: Center returns the position
+// of the new pivot.
+func (t *transform) SetPivot(px, py float32) {
+	t.movePivot(px, py)
+	t.customPivot = true
+}
+
+// Pivot returns the point, in local coordinates, around which the
+// shape rotates and scales.
+func (t *transform) Pivot() (float32, float32) {
+	return t.px, t.py
+}
+
+// Rotate sets the rotation of the shape around its pivot to the
+// given angle in degrees. Scale and position are preserved.
+func (t *transform) Rotate(angle float32) {
+	t.SetRotation(angle)
+}
+
+// SetRotation sets the rotation of the shape around its pivot to
+// the given angle in degrees.
+func (t *transform) SetRotation(angle float32) {
+	t.angle = angle
+	t.changed()
+}
+
+// RotateBy rotates the shape around its pivot by the given angle in
+// degrees, relative to its current rotation.
+func (t *transform) RotateBy(angle float32) {
+	t.SetRotation(t.angle + angle)
+}
+
+// RotateAround sets the rotation of the shape to the given angle in
+// degrees, rotating it around the given point. The pivot of the
+// shape moves accordingly.
+func (t *transform) RotateAround(x, y, angle float32) {
+	s, c := math.Sincos(radians(angle - t.angle))
+	dx, dy := float64(t.x-x), float64(t.y-y)
+	t.x = x + float32(dx*c-dy*s)
+	t.y = y + float32(dx*s+dy*c)
+	t.SetRotation(angle)
+}
+
+// Scale sets the scale factors of the shape relative to its pivot.
+// Rotation and position are preserved.
+func (t *transform) Scale(sx, sy float32) {
+	t.SetScale(sx, sy)
+}
+
+// SetScale sets the scale factors of the shape relative to its
+// pivot.
+func (t *transform) SetScale(sx, sy float32) {
+	t.sx, t.sy = sx, sy
+	t.changed()
+}
+
+// ScaleBy multiplies the current scale factors of the shape by the
+// given ones.
+func (t *transform) ScaleBy(sx, sy float32) {
+	t.SetScale(t.sx*sx, t.sy*sy)
+}
+
+// ScaleFactors returns the current scale factors of the shape.
+func (t *transform) ScaleFactors() (float32, float32) {
+	return t.sx, t.sy
+}
+
+// Skew sets the skew angles of the shape in degrees. The shape is
+// sheared along the x axis by ax and along the y axis by ay, around
+// its pivot.
+func (t *transform) Skew(ax, ay float32) {
+	t.kx, t.ky = ax, ay
+	t.changed()
+}
+
+// SkewAngles returns the current skew angles of the shape in degrees.
+func (t *transform) SkewAngles() (float32, float32) {
+	return t.kx, t.ky
+}
+
+// Transform returns the model matrix of the shape, composed from its
+// position, rotation, skew and scale.
+func (t *transform) Transform() mathgl.Mat4f {
+	t.updateMatrix()
+	return t.matrix
+}
+
+// SetTransform sets the model matrix of the shape, e.g. computed by
+// a physics engine. Only the 2D affine part of m is used: it's
+// decomposed in position, rotation, skew along the x axis and scale,
+// keeping the current pivot, so that the other methods can be used
+// afterwards.
+func (t *transform) SetTransform(m mathgl.Mat4f) {
+	a, b, c, d := float64(m[0]), float64(m[1]), float64(m[4]), float64(m[5])
+
+	// m = R * K * S, with K skewing along the x axis only
+	sx := math.Hypot(a, b)
+	angle := math.Atan2(b, a)
+	sin, cos := math.Sincos(angle)
+	var sy, kx float64
+	if sx != 0 {
+		sy = (a*d - b*c) / sx
+	}
+	if sy != 0 {
+		kx = math.Atan((cos*c + sin*d) / sy)
+	}
+
+	t.angle = float32(angle * 180 / math.Pi)
+	t.sx, t.sy = float32(sx), float32(sy)
+	t.kx, t.ky = float32(kx*180/math.Pi), 0
+	t.x, t.y = transformPoint(m, t.px, t.py)
+	t.changed()
+}
+
+// SetZ sets the layer of the shape. In a frame shapes with a lower z
+// are drawn first, whatever the group containing them. The layer of
+// a shape is relative to the one of its group.
+func (t *transform) SetZ(z float32) {
+	t.z = z
+}
+
+// Z returns the layer of the shape relative to the group containing
+// it.
+func (t *transform) Z() float32 {
+	return t.z
+}
+
+// worldZ returns the layer of the shape added to the ones of the
+// groups containing it.
+func (t *transform) worldZ() float32 {
+	if t.parent == nil {
+		return t.z
+	}
+	return t.z + t.parent.worldZ()
+}
+
+// Move moves the shape by dx, dy.
+func (t *transform) Move(dx, dy float32) {
+	t.SetCenter(t.x+dx, t.y+dy)
+}
+
+// MoveTo moves the shape so that its pivot is in x, y position.
+func (t *transform) MoveTo(x, y float32) {
+	t.SetCenter(x, y)
+}
+
+// Center returns the position of the pivot of the shape, which is
+// its transformed center unless a different pivot was set.
+func (t *transform) Center() (float32, float32) {
+	return t.x, t.y
+}
+
+// SetCenter sets the position of the pivot of the shape.
+func (t *transform) SetCenter(x, y float32) {
+	t.x, t.y = x, y
+	t.changed()
+}
+
+// Angle returns the current angle of the shape in degrees.
+func (t *transform) Angle() float32 {
+	return t.angle
+}
+
+// toLocal transforms (x, y) from world coordinates in the local
+// coordinates of the shape. It returns false if the transform can't
+// be inverted, e.g. because a scale factor is zero.
+func (t *transform) toLocal(x, y float32) (float32, float32, bool) {
+	m := t.worldMatrix()
+	det := m[0]*m[5] - m[1]*m[4]
+	if det == 0 {
+		return 0, 0, false
+	}
+	x, y = x-m[12], y-m[13]
+	return (m[5]*x - m[4]*y) / det, (m[0]*y - m[1]*x) / det, true
+}
+
+// transformPoint applies the 2D part of the transform m to the point
+// (x, y).
+func transformPoint(m mathgl.Mat4f, x, y float32) (float32, float32) {
+	return m[0]*x + m[4]*y + m[12], m[1]*x + m[5]*y + m[13]
+}
@@ -0,0 +1,91 @@
+package shapes
+
+import (
+	gl "github.com/remogatto/opengles2"
+	"github.com/remogatto/shaders"
+)
+
+// Triangle represents a triangle shape.
+type Triangle struct {
+	Base
+}
+
+// NewTriangle creates a new triangle. It takes as arguments a linked
+// program (e.g. built from DefaultBoxVS and DefaultBoxFS) and the
+// coordinates of the three vertices. The center of the triangle is
+// placed at the center of the rectangle bounding the vertices.
+func NewTriangle(program shaders.Program, x1, y1, x2, y2, x3, y3 float32) *Triangle {
+
+	triangle := new(Triangle)
+
+	minX, minY, maxX, maxY := boundsOf([]float32{x1, y1, x2, y2, x3, y3})
+
+	// Center of the triangle
+	triangle.x = (minX + maxX) / 2
+	triangle.y = (minY + maxY) / 2
+
+	// The vertices are stored relative to the center
+	triangle.vertices = []float32{
+		x1 - triangle.x, y1 - triangle.y,
+		x2 - triangle.x, y2 - triangle.y,
+		x3 - triangle.x, y3 - triangle.y,
+	}
+
+	// Set the default color
+	triangle.SetColor(DefaultColor)
+
+	triangle.initTexturedProgram(program)
+
+	// Place the triangle where its vertices were given.
+	triangle.initTransform()
+
+	// Create the bounding rectangle for the shape.
+	triangle.updateBounds()
+
+	return triangle
+}
+
+// SetTexture sets a texture for the triangle. If texCoords is nil
+// the texture coordinates are generated so that the whole texture is
+// mapped on the rectangle bounding the triangle.
+func (triangle *Triangle) SetTexture(texture uint32, texCoords []float32) error {
+	if texCoords == nil {
+		texCoords = triangle.boundsTexCoords()
+	}
+	return triangle.Base.SetTexture(texture, texCoords)
+}
+
+// Contains returns true if (x, y), in world coordinates, lies inside
+// the triangle.
+func (triangle *Triangle) Contains(x, y float32) bool {
+	return triangle.containsLocal(gl.TRIANGLES, nil, x, y)
+}
+
+// hulls returns the triangle as a single convex piece.
+func (triangle *Triangle) hulls() []hull {
+	return triangle.outlineHull(triangle.vertices)
+}
+
+// Raycast returns where the ray from (originX, originY) along
+// (dirX, dirY), in world coordinates, first hits the triangle within
+// maxDist.
+func (triangle *Triangle) Raycast(originX, originY, dirX, dirY, maxDist float32) (RayHit, bool) {
+	return raycast(triangle, originX, originY, dirX, dirY, maxDist)
+}
+
+// Draw actually renders the triangle on the surface.
+func (triangle *Triangle) Draw() {
+	triangle.drawArrays(gl.TRIANGLES)
+}
+
+// Clone makes a copy of the triangle. The copy is centered at the
+// origin.
+func (triangle *Triangle) Clone() Shape {
+	v := triangle.vertices
+	t := NewTriangle(triangle.program, v[0], v[1], v[2], v[3], v[4], v[5])
+	t.copyColors(&triangle.Base)
+	if len(triangle.texCoords) > 0 {
+		t.SetTexture(triangle.texBuffer, triangle.texCoords)
+	}
+	return t
+}